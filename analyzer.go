@@ -0,0 +1,280 @@
+package bouncespy
+
+// Analyzer finds the bounce reasons of messages running an ordered set of
+// detectors. For every recipient, the candidate with the highest confidence
+// is used, and if several candidates have the same confidence the one of the
+// first detector is used.
+type Analyzer struct {
+	detectors []Detector
+	signer    *Signer
+}
+
+var defaultAnalyzer = NewAnalyzer()
+
+// NewAnalyzer returns an Analyzer that uses the given detectors in order. If
+// no detectors are given, the DefaultDetectors are used.
+func NewAnalyzer(detectors ...Detector) *Analyzer {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+
+	return &Analyzer{detectors: detectors}
+}
+
+// Detectors returns the detectors used by the analyzer in order
+func (a *Analyzer) Detectors() []Detector {
+	return append([]Detector(nil), a.detectors...)
+}
+
+// Disable stops using the detectors with the given names
+func (a *Analyzer) Disable(names ...string) {
+	var detectors []Detector
+	for _, d := range a.detectors {
+		var disabled bool
+		for _, name := range names {
+			if d.Name() == name {
+				disabled = true
+				break
+			}
+		}
+
+		if !disabled {
+			detectors = append(detectors, d)
+		}
+	}
+
+	a.detectors = detectors
+}
+
+// VerifySignatures makes the analyzer verify the return path bounces were sent
+// to with the given signer. The status of the verification is reported in
+// the Signature of the results, and the recipient encoded in valid return
+// paths of the own format of the signer is the recipient of the bounce.
+func (a *Analyzer) VerifySignatures(s *Signer) {
+	a.signer = s
+}
+
+// Analyze returns the Result of the message. If the message reports several
+// recipients, the result is the one of the recipient with the worst bounce,
+// that is, a hard bounce over a soft one and a more specific reason over a
+// less specific one. Messages that are not bounces according to Classify,
+// such as out of office messages or complaints, have no reason and their Kind
+// tells what they are. The feedback report of complaints is included in the
+// result.
+func (a *Analyzer) Analyze(m *Message) Result {
+	result := Result{
+		Type:      Unknown,
+		Reason:    NotFound,
+		Category:  CategoryUnknown,
+		SpamScore: SpamScore(m.Header),
+		Spam:      m.SpamReport(),
+		Kind:      m.Kind(),
+		Language:  m.Language(),
+		Original:  m.Original(),
+	}
+
+	if result.Kind == Complaint {
+		result.Feedback, _ = m.FeedbackReport()
+	}
+
+	var signed string
+	result.Signature, signed = a.verifySignature(m)
+	result.Recipient = signed
+
+	if result.Kind == Bounce {
+		recipients, candidates := a.analyze(m, signed)
+		worst := worstResult(recipients)
+
+		result.Reason = worst.Reason
+		result.Recipient = worst.Recipient
+		result.Type = worst.Type
+		result.Action = worst.Action
+		result.Category = worst.Category
+		result.Blocklist = worst.Blocklist
+		result.Confidence = worst.Confidence
+		result.Evidence = worst.Evidence
+		result.Candidates = candidates
+	}
+
+	return result
+}
+
+// AnalyzeRecipients returns a RecipientResult for every recipient the
+// detectors found in the message. If no recipients were found, a single
+// result with no recipient is returned.
+func (a *Analyzer) AnalyzeRecipients(m *Message) []RecipientResult {
+	_, signed := a.verifySignature(m)
+	results, _ := a.analyze(m, signed)
+	return results
+}
+
+// verifySignature returns the status of the signature of the return path the
+// message was sent to and the recipient encoded in it, if it's valid.
+func (a *Analyzer) verifySignature(m *Message) (SignatureStatus, string) {
+	if a.signer == nil {
+		return SignatureUnchecked, ""
+	}
+
+	status, returnPath, addr := a.signer.verifyMessage(m)
+	// BATV return paths contain the sender, not the recipient
+	if status != SignatureValid || isBATV(returnPath) {
+		return status, ""
+	}
+
+	return status, addr
+}
+
+// analyze returns the result of every recipient of the message and all the
+// candidates found by the detectors. The messages sent to a return path that
+// encodes their recipient, either with a valid signature or decoded by a
+// VERP detector, can only be bounces of that recipient, so a single result
+// with the worst bounce of the message is returned for it, no matter the
+// recipients named in the message.
+func (a *Analyzer) analyze(m *Message, signed string) ([]RecipientResult, []Candidate) {
+	results, candidates := a.analyzeCandidates(m)
+
+	returnPath := signed
+	for _, c := range candidates {
+		if returnPath != "" {
+			break
+		}
+
+		if c.Detector == VERPDetector {
+			returnPath = c.Recipient
+		}
+	}
+
+	if returnPath != "" {
+		r := worstResult(results)
+		r.Recipient = returnPath
+		results = []RecipientResult{r}
+	}
+
+	return results, candidates
+}
+
+// worstResult returns the result with the worst bounce, see
+// RecipientResult.isWorseThan
+func worstResult(results []RecipientResult) RecipientResult {
+	var worst RecipientResult
+	for i, r := range results {
+		if i == 0 || r.isWorseThan(worst) {
+			worst = r
+		}
+	}
+
+	return worst
+}
+
+// analyzeCandidates returns the result of every recipient the detectors found
+// in the message and all their candidates.
+func (a *Analyzer) analyzeCandidates(m *Message) ([]RecipientResult, []Candidate) {
+	var recipients []string
+	var candidates []Candidate
+	best := make(map[string]Candidate)
+	for _, d := range a.detectors {
+		for _, c := range d.Detect(m) {
+			c.Detector = d.Name()
+			candidates = append(candidates, c)
+			current, ok := best[c.Recipient]
+			if !ok && c.Recipient != "" {
+				recipients = append(recipients, c.Recipient)
+			}
+
+			// the action and diagnostic of the candidates that are not used
+			// are kept if the best one does not have them
+			if !ok || c.isBetterThan(current) {
+				best[c.Recipient] = mergeCandidates(c, current)
+			} else {
+				best[c.Recipient] = mergeCandidates(current, c)
+			}
+		}
+	}
+
+	general := best[""]
+	if len(recipients) == 0 {
+		return []RecipientResult{general.result(m)}, candidates
+	}
+
+	var found bool
+	for _, r := range recipients {
+		if best[r].Reason != NotFound {
+			found = true
+			break
+		}
+	}
+
+	results := make([]RecipientResult, len(recipients))
+	for i, r := range recipients {
+		c := best[r]
+		// the reasons found for the message as a whole are only used when
+		// no reason could be found for any of the recipients
+		if !found {
+			c.Reason = general.Reason
+			c.Type = general.Type
+			c.Category = general.Category
+			c.Detector = general.Detector
+			c.Rule = general.Rule
+			c.Confidence = general.Confidence
+			c.Evidence = general.Evidence
+			c.Location = general.Location
+			if c.Action == "" {
+				c.Action = general.Action
+			}
+			if c.Diagnostic == "" {
+				c.Diagnostic = general.Diagnostic
+			}
+		}
+
+		results[i] = c.result(m)
+	}
+
+	return results, candidates
+}
+
+// mergeCandidates returns the new candidate keeping the action and diagnostic
+// of the old one if the new one does not have them.
+func mergeCandidates(new, old Candidate) Candidate {
+	if new.Action == "" {
+		new.Action = old.Action
+	}
+
+	if new.Diagnostic == "" {
+		new.Diagnostic = old.Diagnostic
+	}
+
+	return new
+}
+
+func (c Candidate) result(m *Message) RecipientResult {
+	result := newRecipientResult(c.Recipient, c.Reason)
+	if c.Type != nil {
+		result.Type = *c.Type
+	}
+
+	diagnostic := c.Diagnostic
+	if diagnostic == "" {
+		diagnostic = c.Evidence
+	}
+
+	result.Category = c.Category
+	if result.Category == "" {
+		result.Category = Categorize(c.Reason, diagnostic)
+	}
+
+	// rejections caused by a blocklist are a problem of the sender, not of
+	// the recipient, unless a rule says otherwise or the address failed
+	if c.Reason != NotFound {
+		result.Blocklist = ParseBlocklist(diagnostic)
+		if result.Blocklist != nil && c.Type == nil && !isAddressFailure(c.Reason) {
+			result.Type = Soft
+		}
+	}
+	result.Action = c.Action
+	result.Diagnostic = c.Diagnostic
+	if c.isDecisive() {
+		result.Confidence = c.Confidence
+		result.Evidence = newEvidence(m, c)
+	}
+	return result
+}
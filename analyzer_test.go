@@ -0,0 +1,218 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"strings"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestAnalyzerDefaultDetectors(c *ch.C) {
+	a := NewAnalyzer()
+	c.Assert(a.Detectors(), ch.HasLen, len(DefaultDetectors()))
+
+	cases := []struct {
+		msg string
+		r   BounceReason
+	}{
+		{msg1, MailboxUnavailable},
+		{msg2, AddressDoesntExist},
+		{msg3, OtherAddressError},
+		{msg4, NotFound},
+		{msg5, BadDestinationMailboxAddress},
+		{msg6, UndefinedCode},
+		{msg7, NotFound},
+		{msg8, UndefinedCode},
+	}
+
+	for _, cs := range cases {
+		result := a.Analyze(NewMessage(mail.Header{}, []byte(cs.msg)))
+		c.Assert(result.Reason, Equals, cs.r)
+	}
+}
+
+func (s *BounceSuite) TestAnalyzerDisable(c *ch.C) {
+	a := NewAnalyzer()
+	a.Disable(DiagnosticMarkerDetector, "foo")
+	c.Assert(a.Detectors(), ch.HasLen, len(DefaultDetectors())-1)
+	for _, d := range a.Detectors() {
+		c.Assert(d.Name(), ch.Not(Equals), DiagnosticMarkerDetector)
+	}
+
+	result := a.Analyze(NewMessage(mail.Header{}, []byte(msg1)))
+	c.Assert(result.Reason, Equals, UndefinedCode)
+
+	a.Disable(GmailDetector)
+	result = a.Analyze(NewMessage(mail.Header{}, []byte(msg1)))
+	c.Assert(result.Reason, Equals, NotFound)
+}
+
+func (s *BounceSuite) TestAnalyzerCustomDetector(c *ch.C) {
+	quota := NewDetector("quota", func(m *Message) []Candidate {
+		if strings.Contains(m.text(), "over quota") {
+			return []Candidate{{Reason: MailboxFull, Confidence: 0.6, Evidence: "over quota"}}
+		}
+		return nil
+	})
+
+	a := NewAnalyzer(append(DefaultDetectors(), quota)...)
+	result := a.Analyze(NewMessage(mail.Header{}, []byte("Delivery to the following recipient failed permanently:\n\nfoo@foo.foo is over quota")))
+	c.Assert(result.Reason, Equals, MailboxFull)
+	c.Assert(result.Type, Equals, Soft)
+
+	a = NewAnalyzer(quota)
+	result = a.Analyze(NewMessage(mail.Header{}, []byte(msg1)))
+	c.Assert(result.Reason, Equals, NotFound)
+}
+
+func (s *BounceSuite) TestAnalyzerRecipientCandidates(c *ch.C) {
+	recipients := NewDetector("recipients", func(m *Message) []Candidate {
+		return []Candidate{
+			{Recipient: "foo@foo.foo", Reason: MailboxFull, Confidence: 0.5},
+			{Recipient: "bar@foo.foo", Action: "failed"},
+			{Recipient: "foo@foo.foo", Reason: MailboxDisabled, Confidence: 0.6, Action: "failed"},
+			{Recipient: "foo@foo.foo", Reason: BadDestinationMailboxAddress, Confidence: 0.6},
+		}
+	})
+
+	results := NewAnalyzer(recipients).AnalyzeRecipients(NewMessage(mail.Header{}, nil))
+	c.Assert(results, ch.DeepEquals, []RecipientResult{
+		{
+			Recipient:  "foo@foo.foo",
+			Type:       Soft,
+			Reason:     MailboxDisabled,
+			Category:   CategoryInactiveMailbox,
+			Action:     "failed",
+			Confidence: 0.6,
+			Evidence:   &Evidence{Detector: "recipients", Part: -1, Offset: -1},
+		},
+		{
+			Recipient: "bar@foo.foo",
+			Type:      Unknown,
+			Reason:    NotFound,
+			Category:  CategoryUnknown,
+			Action:    "failed",
+			Evidence:  &Evidence{Detector: "recipients", Part: -1, Offset: -1},
+		},
+	})
+
+	// the message reason is only used if no recipient has a reason
+	results = NewAnalyzer(
+		NewDetector("recipients", func(m *Message) []Candidate {
+			return []Candidate{{Recipient: "foo@foo.foo"}, {Recipient: "bar@foo.foo"}}
+		}),
+		NewDetector("message", func(m *Message) []Candidate {
+			return []Candidate{{Reason: MailboxUnavailable, Confidence: 0.1}}
+		}),
+	).AnalyzeRecipients(NewMessage(mail.Header{}, nil))
+	evidence := &Evidence{Detector: "message", Part: -1, Offset: -1}
+	c.Assert(results, ch.DeepEquals, []RecipientResult{
+		{Recipient: "foo@foo.foo", Type: Hard, Reason: MailboxUnavailable, Category: CategoryBadMailbox, Confidence: 0.1, Evidence: evidence},
+		{Recipient: "bar@foo.foo", Type: Hard, Reason: MailboxUnavailable, Category: CategoryBadMailbox, Confidence: 0.1, Evidence: evidence},
+	})
+}
+
+func (s *BounceSuite) TestAnalyzerEvidence(c *ch.C) {
+	result := NewAnalyzer().Analyze(NewMessage(readMessage(c, dsnMsg)))
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Confidence, Equals, deliveryStatusConfidence)
+	c.Assert(result.Evidence, ch.DeepEquals, &Evidence{
+		Detector: DeliveryStatusDetector,
+		Text:     "Status: 5.1.1",
+		Part:     1,
+		Offset:   216,
+	})
+
+	var detectors []string
+	for _, cand := range result.Candidates {
+		detectors = append(detectors, cand.Detector)
+	}
+	c.Assert(detectors, ch.DeepEquals, []string{
+		DeliveryStatusDetector,
+		DeliveryStatusDetector,
+		StatusLineDetector,
+		StatusLineDetector,
+	})
+
+	rs, err := NewRuleSet(Rule{Name: "quota", Match: RuleMatch{Body: "over quota"}, Reason: MailboxFull})
+	c.Assert(err, ch.IsNil)
+
+	body := "Delivery failed\n\nfoo@foo.foo is over quota\n"
+	result = NewAnalyzer(append(DefaultDetectors(), rs)...).Analyze(NewMessage(mail.Header{}, []byte(body)))
+	c.Assert(result.Reason, Equals, MailboxFull)
+	c.Assert(result.Confidence, Equals, defaultRuleConfidence)
+	c.Assert(result.Evidence, ch.DeepEquals, &Evidence{
+		Detector: RulesDetector,
+		Rule:     "quota",
+		Text:     "over quota",
+		Part:     0,
+		Offset:   32,
+	})
+
+	result = NewAnalyzer().Analyze(NewMessage(mail.Header{}, []byte(msg4)))
+	c.Assert(result.Reason, Equals, NotFound)
+	c.Assert(result.Confidence, Equals, 0.0)
+	c.Assert(result.Evidence, ch.IsNil)
+}
+
+func (s *BounceSuite) TestAnalyzerDiagnosticCodeEvidence(c *ch.C) {
+	raw := strings.Replace(dsnMsg, "Status: 5.1.1\r\n", "", 1)
+	header, body := readMessage(c, raw)
+	m := NewMessage(header, body)
+	field := "Diagnostic-Code: smtp; 550 5.1.1 <foo@foo.foo>: Recipient address rejected:\r\n" +
+		"    User unknown in virtual mailbox table"
+
+	result := NewAnalyzer().Analyze(m)
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Confidence, Equals, diagnosticCodeConfidence)
+	c.Assert(result.Evidence, ch.DeepEquals, &Evidence{
+		Detector: DeliveryStatusDetector,
+		Text:     field,
+		Part:     1,
+		Offset:   strings.Index(string(m.Parts[1].Body), field),
+	})
+	c.Assert(result.Evidence.Offset >= 0, Equals, true)
+
+	// the status code that lost against the one used is also a candidate
+	var reasons []BounceReason
+	for _, cand := range result.Candidates {
+		if cand.Recipient == "foo@foo.foo" && cand.Detector == DeliveryStatusDetector {
+			reasons = append(reasons, cand.Reason)
+		}
+	}
+	c.Assert(reasons, ch.DeepEquals, []BounceReason{BadDestinationMailboxAddress, MailboxUnavailable})
+}
+
+func (s *BounceSuite) TestAnalyzerAction(c *ch.C) {
+	cases := []struct {
+		msg    string
+		typ    BounceType
+		action Action
+	}{
+		{msg1, Hard, Failed},
+		{msg4, Unknown, ""},
+		{msg7, Soft, Delayed},
+	}
+
+	for _, cs := range cases {
+		result := Analyze(mail.Header{}, []byte(cs.msg))
+		c.Assert(result.Type, Equals, cs.typ, ch.Commentf("msg: %s", cs.msg))
+		c.Assert(result.Action, Equals, cs.action, ch.Commentf("msg: %s", cs.msg))
+	}
+
+	// the delay notice that decided the type and action is the evidence
+	result := Analyze(mail.Header{}, []byte(msg7))
+	c.Assert(result.Confidence, Equals, gmailConfidence)
+	c.Assert(result.Evidence, ch.DeepEquals, &Evidence{
+		Detector: GmailDetector,
+		Text:     "Delivery to the following recipient has been delayed:",
+		Part:     0,
+		Offset:   strings.Index(msg7, "Delivery to the following recipient has been delayed:"),
+	})
+
+	results := AnalyzeRecipients(readMessage(c, dsnMsg))
+	c.Assert(results, ch.HasLen, 2)
+	c.Assert(results[0].Action, Equals, Failed)
+	c.Assert(results[1].Action, Equals, Delayed)
+	c.Assert(Analyze(readMessage(c, dsnMsg)).Action, Equals, Failed)
+}
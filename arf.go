@@ -0,0 +1,223 @@
+package bouncespy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrNoFeedbackReport is returned when the message is neither an ARF report
+// with a message/feedback-report part nor an X-ARF report
+var ErrNoFeedbackReport = errors.New("bouncespy: no feedback report found")
+
+// FeedbackType is the type of a feedback report according to
+// https://www.iana.org/assignments/marf-parameters/marf-parameters.xhtml
+type FeedbackType string
+
+const (
+	FeedbackAbuse       FeedbackType = "abuse"
+	FeedbackAuthFailure FeedbackType = "auth-failure"
+	FeedbackFraud       FeedbackType = "fraud"
+	FeedbackNotSpam     FeedbackType = "not-spam"
+	FeedbackOther       FeedbackType = "other"
+	FeedbackVirus       FeedbackType = "virus"
+	// FeedbackUnsubscribe is not registered but it's used by some feedback
+	// loops when the recipient asks to be unsubscribed.
+	FeedbackUnsubscribe FeedbackType = "unsubscribe"
+)
+
+// Formats of the feedback reports
+const (
+	FormatARF  = "arf"
+	FormatXARF = "x-arf"
+)
+
+// FeedbackReport contains the fields of a feedback report according to
+// https://tools.ietf.org/html/rfc5965#section-3.1 or of an X-ARF report,
+// see http://x-arf.org/specification.html
+type FeedbackReport struct {
+	// Format is FormatARF or FormatXARF.
+	Format           string       `json:"format"`
+	FeedbackType     FeedbackType `json:"feedback_type"`
+	UserAgent        string       `json:"user_agent,omitempty"`
+	Version          string       `json:"version,omitempty"`
+	OriginalMailFrom string       `json:"original_mail_from,omitempty"`
+	OriginalRcptTo   []string     `json:"original_rcpt_to,omitempty"`
+	SourceIP         string       `json:"source_ip,omitempty"`
+	ArrivalDate      time.Time    `json:"arrival_date"`
+	ReportingMTA     string       `json:"reporting_mta,omitempty"`
+	ReportedDomains  []string     `json:"reported_domains,omitempty"`
+	ReportedURIs     []string     `json:"reported_uris,omitempty"`
+	// OriginalHeader contains the headers of the message the report is
+	// about, if it's included in the report.
+	OriginalHeader mail.Header `json:"original_header,omitempty"`
+}
+
+// ParseFeedbackReport walks the MIME tree of the message with the given headers
+// and body and parses its feedback report. ErrNoFeedbackReport is returned if
+// the message is not a feedback report.
+func ParseFeedbackReport(headers mail.Header, body []byte) (*FeedbackReport, error) {
+	return NewMessage(headers, body).FeedbackReport()
+}
+
+// FeedbackReport parses the ARF or X-ARF feedback report of the message.
+// ErrNoFeedbackReport is returned if the message is not a feedback report.
+func (m *Message) FeedbackReport() (*FeedbackReport, error) {
+	var report *FeedbackReport
+	var err error
+	for _, p := range m.Parts {
+		if p.MediaType == "message/feedback-report" {
+			report, err = parseFeedbackReport(p.Body)
+			break
+		}
+	}
+
+	if report == nil && err == nil && isXARF(m.Header) {
+		report, err = m.parseXARF()
+	}
+
+	if err != nil {
+		return nil, err
+	} else if report == nil {
+		return nil, ErrNoFeedbackReport
+	}
+
+	if original := m.Original(); original != nil {
+		report.OriginalHeader = original.Header
+	}
+
+	return report, nil
+}
+
+func parseFeedbackReport(content []byte) (*FeedbackReport, error) {
+	fields, err := readFields(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var rcpts []string
+	for _, v := range fields["Original-Rcpt-To"] {
+		rcpts = append(rcpts, strings.Trim(strings.TrimSpace(v), "<>"))
+	}
+
+	return &FeedbackReport{
+		Format:           FormatARF,
+		FeedbackType:     FeedbackType(strings.ToLower(strings.TrimSpace(fields.Get("Feedback-Type")))),
+		UserAgent:        strings.TrimSpace(fields.Get("User-Agent")),
+		Version:          strings.TrimSpace(fields.Get("Version")),
+		OriginalMailFrom: strings.Trim(strings.TrimSpace(fields.Get("Original-Mail-From")), "<>"),
+		OriginalRcptTo:   rcpts,
+		SourceIP:         strings.TrimSpace(fields.Get("Source-Ip")),
+		ArrivalDate:      fieldDate(fields, firstField(fields, "Arrival-Date", "Received-Date")),
+		ReportingMTA:     fieldValue(fields, "Reporting-MTA"),
+		ReportedDomains:  trimValues(fields["Reported-Domain"]),
+		ReportedURIs:     trimValues(fields["Reported-Uri"]),
+	}, nil
+}
+
+// isXARF reports whether the message has the X-ARF: Yes header
+func isXARF(header mail.Header) bool {
+	return strings.EqualFold(strings.TrimSpace(header.Get("X-Arf")), "yes")
+}
+
+// parseXARF parses the YAML report of an X-ARF message, which is the text
+// part named report.txt or, if there is none, the second text/plain part.
+func (m *Message) parseXARF() (*FeedbackReport, error) {
+	var content []byte
+	var plain int
+	for _, p := range m.Parts {
+		if p.MediaType != "text/plain" {
+			continue
+		}
+
+		plain++
+		if strings.EqualFold(p.Params["name"], "report.txt") {
+			content = p.Body
+			break
+		} else if plain == 2 && content == nil {
+			content = p.Body
+		}
+	}
+
+	if content == nil {
+		return nil, ErrNoFeedbackReport
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("bouncespy: invalid X-ARF report: %s", err)
+	}
+
+	value := func(key string) string {
+		for k, v := range fields {
+			if strings.EqualFold(k, key) && v != nil {
+				return strings.TrimSpace(fmt.Sprint(v))
+			}
+		}
+
+		return ""
+	}
+
+	typ := value("Report-Type")
+	if typ == "" {
+		typ = value("Category")
+	}
+
+	report := &FeedbackReport{
+		Format:       FormatXARF,
+		FeedbackType: FeedbackType(strings.ToLower(typ)),
+		UserAgent:    value("User-Agent"),
+		Version:      value("Version"),
+	}
+
+	switch strings.ToLower(value("Source-Type")) {
+	case "ipv4", "ipv6", "ip-address":
+		report.SourceIP = value("Source")
+	}
+
+	if date, err := mail.ParseDate(value("Date")); err == nil {
+		report.ArrivalDate = date
+	}
+
+	return report, nil
+}
+
+// readFields reads the first block of header fields of the content. The
+// block does not need to end with an empty line.
+func readFields(content []byte) (textproto.MIMEHeader, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(content)))
+	fields, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// firstField returns the first of the given keys present in the fields
+func firstField(fields textproto.MIMEHeader, keys ...string) string {
+	for _, key := range keys {
+		if fields.Get(key) != "" {
+			return key
+		}
+	}
+
+	return keys[0]
+}
+
+func trimValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		result = append(result, strings.TrimSpace(v))
+	}
+
+	return result
+}
@@ -0,0 +1,171 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"time"
+
+	ch "gopkg.in/check.v1"
+)
+
+var arfMsg = `From: <abusedesk@example.com>
+Date: Thu, 8 Mar 2005 17:40:36 EDT
+Subject: FW: Earn money
+To: <abuse@example.net>
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=feedback-report;
+     boundary="part1_13d.2e68ed54_boundary"
+
+--part1_13d.2e68ed54_boundary
+Content-Type: text/plain; charset="US-ASCII"
+Content-Transfer-Encoding: 7bit
+
+This is an email abuse report for an email message received from IP
+192.0.2.1 on Thu, 8 Mar 2005 14:00:00 EDT.  For more information
+about this format please see http://www.mipassoc.org/arf/.
+
+--part1_13d.2e68ed54_boundary
+Content-Type: message/feedback-report
+
+Feedback-Type: abuse
+User-Agent: SomeGenerator/1.0
+Version: 1
+Original-Mail-From: <somespammer@example.net>
+Original-Rcpt-To: <user@example.com>
+Arrival-Date: Thu, 8 Mar 2005 14:00:00 -0400
+Reporting-MTA: dns; mail.example.com
+Source-IP: 192.0.2.1
+Authentication-Results: mail.example.com;
+               spf=fail smtp.mail=somespammer@example.com
+Reported-Domain: example.net
+Reported-Uri: http://example.net/earn_money.html
+Reported-Uri: mailto:user@example.com
+Removal-Recipient: user@example.com
+
+--part1_13d.2e68ed54_boundary
+Content-Type: message/rfc822
+Content-Disposition: inline
+
+From: <somespammer@example.net>
+Received: from mailserver.example.net (mailserver.example.net
+        [192.0.2.1]) by example.com with ESMTP id M63d4137594e46;
+        Thu, 08 Mar 2005 14:00:00 -0400
+To: <Undisclosed Recipients>
+Subject: Earn money
+MIME-Version: 1.0
+Content-type: text/plain
+Message-ID: 8787KJKJ3K4J3K4J3K4J3.mail@example.net
+Date: Thu, 02 Sep 2004 12:31:03 -0500
+
+Spam Spam Spam
+Spam Spam Spam
+--part1_13d.2e68ed54_boundary--
+`
+
+var xarfMsg = `From: <abuse@example.com>
+To: <abuse@example.net>
+Subject: abuse report about 192.0.2.1
+X-ARF: Yes
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="Abuse-bc5a5ef2"
+
+--Abuse-bc5a5ef2
+Content-Type: text/plain; charset=utf-8
+
+Dear Sir or Madam,
+
+we received a spam message from a host of your network.
+
+--Abuse-bc5a5ef2
+Content-Type: text/plain; charset=utf-8; name="report.txt"
+
+Reported-From: abuse@example.com
+Category: abuse
+Report-Type: spam
+Service: smtp
+Version: 0.2
+User-Agent: Abusereporter 1.0
+Date: Thu, 8 Mar 2005 14:00:00 +0000
+Source-Type: ipv4
+Source: 192.0.2.1
+Port: 25
+
+--Abuse-bc5a5ef2
+Content-Type: message/rfc822
+
+From: <somespammer@example.net>
+Subject: Earn money
+
+Spam Spam Spam
+--Abuse-bc5a5ef2--
+`
+
+func (s *BounceSuite) TestParseFeedbackReport(c *ch.C) {
+	report, err := ParseFeedbackReport(readMessage(c, arfMsg))
+	c.Assert(err, ch.IsNil)
+
+	c.Assert(report.OriginalHeader.Get("Message-Id"), Equals, "8787KJKJ3K4J3K4J3K4J3.mail@example.net")
+	c.Assert(report.OriginalHeader.Get("Subject"), Equals, "Earn money")
+	report.OriginalHeader = nil
+
+	arrival := time.Date(2005, time.March, 8, 18, 0, 0, 0, time.UTC)
+	c.Assert(report.ArrivalDate.Equal(arrival), Equals, true)
+	report.ArrivalDate = time.Time{}
+
+	c.Assert(report, ch.DeepEquals, &FeedbackReport{
+		Format:           FormatARF,
+		FeedbackType:     FeedbackAbuse,
+		UserAgent:        "SomeGenerator/1.0",
+		Version:          "1",
+		OriginalMailFrom: "somespammer@example.net",
+		OriginalRcptTo:   []string{"user@example.com"},
+		SourceIP:         "192.0.2.1",
+		ReportingMTA:     "mail.example.com",
+		ReportedDomains:  []string{"example.net"},
+		ReportedURIs:     []string{"http://example.net/earn_money.html", "mailto:user@example.com"},
+	})
+}
+
+func (s *BounceSuite) TestParseXARFReport(c *ch.C) {
+	report, err := ParseFeedbackReport(readMessage(c, xarfMsg))
+	c.Assert(err, ch.IsNil)
+
+	c.Assert(report.OriginalHeader.Get("From"), Equals, "<somespammer@example.net>")
+	report.OriginalHeader = nil
+
+	arrival := time.Date(2005, time.March, 8, 14, 0, 0, 0, time.UTC)
+	c.Assert(report.ArrivalDate.Equal(arrival), Equals, true)
+	report.ArrivalDate = time.Time{}
+
+	c.Assert(report, ch.DeepEquals, &FeedbackReport{
+		Format:       FormatXARF,
+		FeedbackType: "spam",
+		UserAgent:    "Abusereporter 1.0",
+		Version:      "0.2",
+		SourceIP:     "192.0.2.1",
+	})
+
+	_, err = ParseFeedbackReport(mail.Header{"X-Arf": {"yes"}}, []byte("foo"))
+	c.Assert(err, Equals, ErrNoFeedbackReport)
+}
+
+func (s *BounceSuite) TestParseFeedbackReportNotFound(c *ch.C) {
+	_, err := ParseFeedbackReport(readMessage(c, dsnMsg))
+	c.Assert(err, Equals, ErrNoFeedbackReport)
+
+	_, err = ParseFeedbackReport(mail.Header{}, []byte(msg1))
+	c.Assert(err, Equals, ErrNoFeedbackReport)
+}
+
+func (s *BounceSuite) TestAnalyzeComplaint(c *ch.C) {
+	result := Analyze(readMessage(c, arfMsg))
+	c.Assert(result.Kind, Equals, Complaint)
+	c.Assert(result.Reason, Equals, NotFound)
+	c.Assert(result.Feedback, ch.NotNil)
+	c.Assert(result.Feedback.FeedbackType, Equals, FeedbackAbuse)
+
+	result = Analyze(readMessage(c, xarfMsg))
+	c.Assert(result.Kind, Equals, Complaint)
+	c.Assert(result.Feedback.Format, Equals, FormatXARF)
+
+	c.Assert(Analyze(readMessage(c, dsnMsg)).Feedback, ch.IsNil)
+}
@@ -0,0 +1,129 @@
+package bouncespy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Blocklist is a DNS blocklist or reputation list that caused a message to
+// be rejected. Bounces caused by a blocklist are a problem of the sender, so
+// they are soft bounces with CategoryReputation, unless their status code is
+// an address failure, such as 5.1.1.
+type Blocklist struct {
+	// Name is the name of the list, such as "Spamhaus", or its zone if it's
+	// not a known one.
+	Name string `json:"name"`
+	// Zone is the DNS zone that was queried, such as "zen.spamhaus.org", if
+	// the diagnostic names it.
+	Zone string `json:"zone,omitempty"`
+	// Listed is the IP address or domain that is listed, if the diagnostic
+	// names it.
+	Listed string `json:"listed,omitempty"`
+	// URL is the page to look up or delist the listed address.
+	URL string `json:"url,omitempty"`
+}
+
+// knownBlocklists are the blocklists recognized by the lowercase markers of
+// the diagnostics. The %s of the lookup URLs is replaced with the listed
+// address.
+var knownBlocklists = []struct {
+	name    string
+	markers []string
+	lookup  string
+}{
+	{"Spamhaus", []string{"spamhaus"}, "https://check.spamhaus.org/listed/?searchterm=%s"},
+	{"Barracuda", []string{"barracudacentral", "barracuda reputation"}, "https://www.barracudacentral.org/lookups/lookup-reputation"},
+	{"SpamCop", []string{"spamcop"}, "https://www.spamcop.net/w3m?action=checkblock&ip=%s"},
+	{"SORBS", []string{"sorbs.net", "sorbs "}, "http://www.sorbs.net/lookup.shtml?%s"},
+	{"UCEPROTECT", []string{"uceprotect"}, "https://www.uceprotect.net/en/rblcheck.php?ipr=%s"},
+	{"Microsoft", []string{"s3150", "s3140"}, "https://sender.office.com/"},
+}
+
+var (
+	// blocklistZoneRe matches the zone named in diagnostics such as "blocked
+	// using zen.spamhaus.org" or "listed at bl.spamcop.net"
+	blocklistZoneRe = regexp.MustCompile(`(?i)(blocked using|listed (?:in|at|on|by)|rejected by)\s+([a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,})`)
+	// dnsblLabelRe matches the labels of the zones of DNS blocklists, such
+	// as "dnsbl", "rbl" or "bl" in bl.foo.foo
+	dnsblLabelRe = regexp.MustCompile(`(?i)(?:^|[.-])(?:[a-z]?bl|dnsbl|rbl|rbls|blacklist|blocklist|blackhole)s?\d*(?:[.-]|$)`)
+	// blocklistWordsRe matches the words that explicitly say the sender is in
+	// a blocklist
+	blocklistWordsRe = regexp.MustCompile(`(?i)\b(?:black|block)[- ]?list(?:ed)?\b|\b(?:dnsbl|rbl)\b`)
+	blocklistURLRe   = regexp.MustCompile(`(?i)https?://[^\s<>"'()\[\]]+`)
+	ipv4Re           = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	listedDomainRe   = regexp.MustCompile(`(?i)[?&]domain=([a-z0-9.-]+)`)
+)
+
+// ParseBlocklist returns the blocklist a diagnostic says the message was
+// rejected by, or nil if it does not mention any. Only known blocklists and
+// the zones named with explicit wording, such as "blocked using" or "listed
+// at" a DNSBL zone, are reported, so the host names of the servers that
+// rejected a message are not taken for blocklists.
+func ParseBlocklist(diagnostic string) *Blocklist {
+	lower := strings.ToLower(diagnostic)
+
+	var b Blocklist
+	explicit := blocklistWordsRe.MatchString(diagnostic)
+	for _, m := range blocklistZoneRe.FindAllStringSubmatch(diagnostic, -1) {
+		if strings.EqualFold(m[1], "blocked using") || dnsblLabelRe.MatchString(m[2]) || explicit {
+			b.Zone = strings.ToLower(m[2])
+			break
+		}
+	}
+
+	var lookup, marker string
+	for _, l := range knownBlocklists {
+		for _, mk := range l.markers {
+			if strings.Contains(lower, mk) {
+				b.Name, lookup, marker = l.name, l.lookup, strings.TrimSpace(mk)
+				break
+			}
+		}
+
+		if b.Name != "" {
+			break
+		}
+	}
+
+	if b.Name == "" {
+		if b.Zone == "" {
+			return nil
+		}
+		b.Name = b.Zone
+	}
+
+	// the listed address is the last one of the diagnostic, as the ones
+	// before are usually the ones of the remote host that rejected the
+	// message
+	if ips := ipv4Re.FindAllString(diagnostic, -1); len(ips) > 0 {
+		b.Listed = ips[len(ips)-1]
+	} else if m := listedDomainRe.FindStringSubmatch(diagnostic); m != nil {
+		b.Listed = strings.ToLower(m[1])
+	}
+
+	for _, u := range blocklistURLRe.FindAllString(diagnostic, -1) {
+		u = strings.TrimRight(u, ".,;:")
+		if marker == "" || strings.Contains(strings.ToLower(u), marker) {
+			b.URL = u
+			break
+		}
+	}
+
+	if b.URL == "" && lookup != "" {
+		if strings.Contains(lookup, "%s") {
+			lookup = fmt.Sprintf(lookup, b.Listed)
+		}
+		b.URL = lookup
+	}
+
+	return &b
+}
+
+// isAddressFailure reports whether the reason is a permanent failure of the
+// address of the recipient, that is, a 5.1.X status code, which is not caused
+// by a blocklist even if the diagnostic mentions one.
+func isAddressFailure(reason BounceReason) bool {
+	code, err := ParseEnhancedStatusCode(string(reason))
+	return err == nil && code.Class == 5 && code.Subject == 1
+}
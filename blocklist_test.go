@@ -0,0 +1,118 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+var blocklistMsg = `This is the mail system at host mx.bar.bar.
+
+I'm sorry to have to inform you that your message could not
+be delivered to one or more recipients.
+
+<foo@foo.foo>: host mx.foo.foo[1.1.1.1] said: 554 5.7.1 Service unavailable; Client host [2.2.2.2] blocked using zen.spamhaus.org; https://www.spamhaus.org/query/ip/2.2.2.2
+    (in reply to RCPT TO command)
+`
+
+func (s *BounceSuite) TestParseBlocklist(c *ch.C) {
+	cases := []struct {
+		diagnostic string
+		blocklist  *Blocklist
+	}{
+		{
+			"554 5.7.1 Service unavailable; Client host [1.2.3.4] blocked using zen.spamhaus.org",
+			&Blocklist{Name: "Spamhaus", Zone: "zen.spamhaus.org", Listed: "1.2.3.4", URL: "https://check.spamhaus.org/listed/?searchterm=1.2.3.4"},
+		},
+		{
+			"550 5.7.1 Mail rejected; https://www.spamhaus.org/query/dbl?domain=Foo.foo",
+			&Blocklist{Name: "Spamhaus", Listed: "foo.foo", URL: "https://www.spamhaus.org/query/dbl?domain=Foo.foo"},
+		},
+		{
+			"554 Service unavailable; Client host [1.2.3.4] blocked using Barracuda Reputation, http://www.barracudanetworks.com/reputation/?pr=1&ip=1.2.3.4",
+			&Blocklist{Name: "Barracuda", Listed: "1.2.3.4", URL: "https://www.barracudacentral.org/lookups/lookup-reputation"},
+		},
+		{
+			"550 5.7.1 Blocked - see https://www.spamcop.net/bl.shtml?1.2.3.4",
+			&Blocklist{Name: "SpamCop", Listed: "1.2.3.4", URL: "https://www.spamcop.net/bl.shtml?1.2.3.4"},
+		},
+		{
+			"550 Rejected: 1.2.3.4 listed at dnsbl.sorbs.net",
+			&Blocklist{Name: "SORBS", Zone: "dnsbl.sorbs.net", Listed: "1.2.3.4", URL: "http://www.sorbs.net/lookup.shtml?1.2.3.4"},
+		},
+		{
+			"550 Your IP 1.2.3.4 is listed in dnsbl-1.uceprotect.net",
+			&Blocklist{Name: "UCEPROTECT", Zone: "dnsbl-1.uceprotect.net", Listed: "1.2.3.4", URL: "https://www.uceprotect.net/en/rblcheck.php?ipr=1.2.3.4"},
+		},
+		{
+			"550 5.7.1 Unfortunately, messages from [1.2.3.4] weren't sent. Please contact your Internet service provider since part of their network is on our block list (S3150).",
+			&Blocklist{Name: "Microsoft", Listed: "1.2.3.4", URL: "https://sender.office.com/"},
+		},
+		{
+			"554 5.7.1 Client host [1.2.3.4] blocked using bl.foo.foo",
+			&Blocklist{Name: "bl.foo.foo", Zone: "bl.foo.foo", Listed: "1.2.3.4"},
+		},
+		{
+			"554 5.7.1 Rejected, 1.2.3.4 is listed on rbl.foo.foo",
+			&Blocklist{Name: "rbl.foo.foo", Zone: "rbl.foo.foo", Listed: "1.2.3.4"},
+		},
+		{
+			"550 5.7.1 Your IP 1.2.3.4 is blacklisted, rejected by mail.foo.foo",
+			&Blocklist{Name: "mail.foo.foo", Zone: "mail.foo.foo", Listed: "1.2.3.4"},
+		},
+		{"550 5.1.1 User unknown", nil},
+		{"550 foo@foo.foo is not listed in public Name & Address Book", nil},
+		{"550 5.1.1 <foo@foo.foo> rejected by mx2.foo.foo: user unknown", nil},
+		{"550 5.7.1 Message from 1.2.3.4 rejected by mx.foo.foo", nil},
+		{"550 Your domain is listed at foo.foo as a partner", nil},
+	}
+
+	for _, cs := range cases {
+		c.Assert(ParseBlocklist(cs.diagnostic), ch.DeepEquals, cs.blocklist, ch.Commentf("diagnostic: %s", cs.diagnostic))
+	}
+}
+
+func (s *BounceSuite) TestAnalyzeBlocklist(c *ch.C) {
+	result := Analyze(mail.Header{}, []byte(blocklistMsg))
+	c.Assert(result.Reason, Equals, MessageRefused)
+	c.Assert(result.Recipient, Equals, "foo@foo.foo")
+	c.Assert(result.Type, Equals, Soft)
+	c.Assert(result.Category, Equals, CategoryReputation)
+	c.Assert(result.Blocklist, ch.DeepEquals, &Blocklist{
+		Name:   "Spamhaus",
+		Zone:   "zen.spamhaus.org",
+		Listed: "2.2.2.2",
+		URL:    "https://www.spamhaus.org/query/ip/2.2.2.2",
+	})
+
+	result = Analyze(mail.Header{}, []byte(postfixMsg))
+	c.Assert(result.Type, Equals, Hard)
+	c.Assert(result.Blocklist, ch.IsNil)
+
+	result = Analyze(mail.Header{}, []byte("<foo@foo.foo>: host mx.foo.foo[1.1.1.1] said: 550 5.1.1 <foo@foo.foo> rejected by mx2.foo.foo: user unknown"))
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Type, Equals, Hard)
+	c.Assert(result.Category, Equals, CategoryBadMailbox)
+	c.Assert(result.Blocklist, ch.IsNil)
+
+	// address failures are hard bounces even if a blocklist is mentioned
+	result = Analyze(mail.Header{}, []byte("<foo@foo.foo>: host mx.foo.foo[1.1.1.1] said: 550 5.1.1 user unknown, see spamhaus.org"))
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Type, Equals, Hard)
+	c.Assert(result.Blocklist, ch.NotNil)
+}
+
+func (s *BounceSuite) TestAnalyzeBlocklistRuleType(c *ch.C) {
+	rs, err := NewRuleSet(Rule{
+		Name:   "spamhaus",
+		Match:  RuleMatch{Body: "spamhaus"},
+		Reason: MessageRefused,
+		Type:   "hard",
+	})
+	c.Assert(err, ch.IsNil)
+
+	result := NewAnalyzer(rs).Analyze(NewMessage(mail.Header{}, []byte(blocklistMsg)))
+	c.Assert(result.Type, Equals, Hard)
+	c.Assert(result.Blocklist, ch.NotNil)
+	c.Assert(result.Blocklist.Name, Equals, "Spamhaus")
+}
@@ -1,7 +1,10 @@
 package bouncespy
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"net/mail"
 	"strconv"
 	"strings"
@@ -13,27 +16,61 @@ type BounceType int
 const (
 	Soft BounceType = 0
 	Hard BounceType = 1
+	// Unknown is the type of the bounces whose reason could not be found, so
+	// it's not known whether they are hard or soft.
+	Unknown BounceType = 2
 )
 
+var bounceTypeNames = map[BounceType]string{
+	Soft:    "soft",
+	Hard:    "hard",
+	Unknown: "unknown",
+}
+
+func (t BounceType) String() string {
+	return bounceTypeNames[t]
+}
+
+func parseBounceType(s string) (BounceType, error) {
+	for t, name := range bounceTypeNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return t, nil
+		}
+	}
+
+	return Unknown, fmt.Errorf("bouncespy: invalid bounce type %q", s)
+}
+
 // BounceReason is a status code that tells why the message was bounced according to
-// https://tools.ietf.org/html/rfc3463#section-3 and https://tools.ietf.org/html/rfc821#section-4.2.2
+// https://tools.ietf.org/html/rfc3463#section-3, https://tools.ietf.org/html/rfc5321#section-4.2.2
+// and https://tools.ietf.org/html/rfc7504#section-3
 type BounceReason string
 
 const (
 	ServiceNotAvailable               BounceReason = "421"
+	PasswordTransitionRequired        BounceReason = "432"
 	MailActionNotTaken                BounceReason = "450"
 	ActionAbortedErrorProcessing      BounceReason = "451"
 	ActionAbortedInsufficientStorage  BounceReason = "452"
+	TemporaryAuthFailure              BounceReason = "454"
+	ParamsNotAccommodated             BounceReason = "455"
 	CmdSyntaxError                    BounceReason = "500"
 	ArgumentsSyntaxError              BounceReason = "501"
 	CmdNotImplemented                 BounceReason = "502"
 	BadCmdSequence                    BounceReason = "503"
 	CmdParamNotImplemented            BounceReason = "504"
+	HostNotAcceptingMail              BounceReason = "521"
+	AuthRequired                      BounceReason = "530"
+	AuthMechTooWeak                   BounceReason = "534"
+	AuthInvalidCredentials            BounceReason = "535"
+	AuthEncryptionRequired            BounceReason = "538"
 	MailboxUnavailable                BounceReason = "550"
 	RecipientNotLocal                 BounceReason = "551"
 	ActionAbortedExceededStorageAlloc BounceReason = "552"
 	MailboxNameInvalid                BounceReason = "553"
 	TransactionFailed                 BounceReason = "554"
+	MailRcptParamsNotImplemented      BounceReason = "555"
+	DomainNotAcceptingMail            BounceReason = "556"
 
 	AddressDoesntExist                       BounceReason = "5.0.0"
 	OtherAddressError                        BounceReason = "5.1.0"
@@ -83,149 +120,132 @@ const (
 	CryptoFailure                            BounceReason = "5.7.5"
 	CryptoAlgorithmNotSupported              BounceReason = "5.7.6"
 	MessageIntegrityFailure                  BounceReason = "5.7.7"
+	MessageRelayedToNonCompliantMailer       BounceReason = "5.1.9"
+	RecipientAddressHasNullMX                BounceReason = "5.1.10"
+	SystemIncorrectlyConfigured              BounceReason = "5.3.5"
+	RequestedPriorityChanged                 BounceReason = "5.3.6"
+	AuthExchangeLineTooLong                  BounceReason = "5.5.6"
+	MessageContentNotAvailable               BounceReason = "5.6.6"
+	NonASCIIAddressesNotPermitted            BounceReason = "5.6.7"
+	UTF8ReplyRequired                        BounceReason = "5.6.8"
+	UTF8HeaderNotTransferable                BounceReason = "5.6.9"
+	AuthCredentialsInvalid                   BounceReason = "5.7.8"
+	AuthMechanismTooWeak                     BounceReason = "5.7.9"
+	EncryptionNeeded                         BounceReason = "5.7.10"
+	EncryptionRequiredForAuthMechanism       BounceReason = "5.7.11"
+	PasswordTransitionNeeded                 BounceReason = "5.7.12"
+	UserAccountDisabled                      BounceReason = "5.7.13"
+	TrustRelationshipRequired                BounceReason = "5.7.14"
+	PriorityLevelTooLow                      BounceReason = "5.7.15"
+	MessageTooBigForPriority                 BounceReason = "5.7.16"
+	MailboxOwnerChanged                      BounceReason = "5.7.17"
+	DomainOwnerChanged                       BounceReason = "5.7.18"
+	RRVSTestNotCompleted                     BounceReason = "5.7.19"
+	NoPassingDKIMSignature                   BounceReason = "5.7.20"
+	NoAcceptableDKIMSignature                BounceReason = "5.7.21"
+	NoAuthorMatchedDKIMSignature             BounceReason = "5.7.22"
+	SPFValidationFailed                      BounceReason = "5.7.23"
+	SPFValidationError                       BounceReason = "5.7.24"
+	ReverseDNSValidationFailed               BounceReason = "5.7.25"
+	MultipleAuthChecksFailed                 BounceReason = "5.7.26"
+	SenderAddressHasNullMX                   BounceReason = "5.7.27"
+	ARCValidationFailure                     BounceReason = "5.7.29"
+	RequireTLSSupportRequired                BounceReason = "5.7.30"
 	UndefinedCode                            BounceReason = "9.1.1"
 
+	// Persistent transient failures, the message as sent is valid but it may
+	// be delivered if it's sent again later
+	TransientAddressDoesntExist                       BounceReason = "4.0.0"
+	TransientOtherAddressError                        BounceReason = "4.1.0"
+	TransientBadDestinationMailboxAddress             BounceReason = "4.1.1"
+	TransientBadDestinationSystemAddress              BounceReason = "4.1.2"
+	TransientBadDestinationMailboxAddressSyntax       BounceReason = "4.1.3"
+	TransientDestinationMailboxAmbiguous              BounceReason = "4.1.4"
+	TransientDestinationMailboxAddressInvalid         BounceReason = "4.1.5"
+	TransientMailboxMoved                             BounceReason = "4.1.6"
+	TransientBadSenderMailboxAddressSyntax            BounceReason = "4.1.7"
+	TransientBadSenderSystemAddress                   BounceReason = "4.1.8"
+	TransientUndefinedMailboxError                    BounceReason = "4.2.0"
+	TransientMailboxDisabled                          BounceReason = "4.2.1"
+	TransientMailboxFull                              BounceReason = "4.2.2"
+	TransientMessageLenExceedsLimit                   BounceReason = "4.2.3"
+	TransientMailingListExpansionProblem              BounceReason = "4.2.4"
+	TransientUndefinedMailSystemStatus                BounceReason = "4.3.0"
+	TransientMailSystemFull                           BounceReason = "4.3.1"
+	TransientSystemNotAcceptingNetworkMessages        BounceReason = "4.3.2"
+	TransientSystemNotCapableOfFeatures               BounceReason = "4.3.3"
+	TransientMessageTooBigForSystem                   BounceReason = "4.3.4"
+	TransientUndefinedNetworkStatus                   BounceReason = "4.4.0"
+	TransientNoAnswerFromHost                         BounceReason = "4.4.1"
+	TransientBadConnection                            BounceReason = "4.4.2"
+	TransientRoutingServerFailure                     BounceReason = "4.4.3"
+	TransientUnableToRoute                            BounceReason = "4.4.4"
+	TransientNetworkCongestion                        BounceReason = "4.4.5"
+	TransientRoutingLoopDetected                      BounceReason = "4.4.6"
+	TransientDeliveryTimeExpired                      BounceReason = "4.4.7"
+	TransientUndefinedProtocolStatus                  BounceReason = "4.5.0"
+	TransientInvalidCommand                           BounceReason = "4.5.1"
+	TransientSyntaxError                              BounceReason = "4.5.2"
+	TransientTooManyRecipients                        BounceReason = "4.5.3"
+	TransientInvalidCommandArguments                  BounceReason = "4.5.4"
+	TransientWrongProtocolVersion                     BounceReason = "4.5.5"
+	TransientUndefinedMediaError                      BounceReason = "4.6.0"
+	TransientMediaNotSupported                        BounceReason = "4.6.1"
+	TransientConversionRequiredAndProhibited          BounceReason = "4.6.2"
+	TransientConversionRequiredButNotSupported        BounceReason = "4.6.3"
+	TransientConversionWithLossPerformed              BounceReason = "4.6.4"
+	TransientConversionFailed                         BounceReason = "4.6.5"
+	TransientUndefinedSecurityStatus                  BounceReason = "4.7.0"
+	TransientMessageRefused                           BounceReason = "4.7.1"
+	TransientMailingListExpansionProhibited           BounceReason = "4.7.2"
+	TransientSecurityConversionRequiredButNotPossible BounceReason = "4.7.3"
+	TransientSecurityFeaturesNotSupported             BounceReason = "4.7.4"
+	TransientCryptoFailure                            BounceReason = "4.7.5"
+	TransientCryptoAlgorithmNotSupported              BounceReason = "4.7.6"
+	TransientMessageIntegrityFailure                  BounceReason = "4.7.7"
+	TransientMessageRelayedToNonCompliantMailer       BounceReason = "4.1.9"
+	TransientRecipientAddressHasNullMX                BounceReason = "4.1.10"
+	TransientSystemIncorrectlyConfigured              BounceReason = "4.3.5"
+	TransientRequestedPriorityChanged                 BounceReason = "4.3.6"
+	TransientAuthExchangeLineTooLong                  BounceReason = "4.5.6"
+	TransientMessageContentNotAvailable               BounceReason = "4.6.6"
+	TransientNonASCIIAddressesNotPermitted            BounceReason = "4.6.7"
+	TransientUTF8ReplyRequired                        BounceReason = "4.6.8"
+	TransientUTF8HeaderNotTransferable                BounceReason = "4.6.9"
+	TransientAuthCredentialsInvalid                   BounceReason = "4.7.8"
+	TransientAuthMechanismTooWeak                     BounceReason = "4.7.9"
+	TransientEncryptionNeeded                         BounceReason = "4.7.10"
+	TransientEncryptionRequiredForAuthMechanism       BounceReason = "4.7.11"
+	TransientPasswordTransitionNeeded                 BounceReason = "4.7.12"
+	TransientUserAccountDisabled                      BounceReason = "4.7.13"
+	TransientTrustRelationshipRequired                BounceReason = "4.7.14"
+	TransientPriorityLevelTooLow                      BounceReason = "4.7.15"
+	TransientMessageTooBigForPriority                 BounceReason = "4.7.16"
+	TransientMailboxOwnerChanged                      BounceReason = "4.7.17"
+	TransientDomainOwnerChanged                       BounceReason = "4.7.18"
+	TransientRRVSTestNotCompleted                     BounceReason = "4.7.19"
+	TransientNoPassingDKIMSignature                   BounceReason = "4.7.20"
+	TransientNoAcceptableDKIMSignature                BounceReason = "4.7.21"
+	TransientNoAuthorMatchedDKIMSignature             BounceReason = "4.7.22"
+	TransientSPFValidationFailed                      BounceReason = "4.7.23"
+	TransientSPFValidationError                       BounceReason = "4.7.24"
+	TransientReverseDNSValidationFailed               BounceReason = "4.7.25"
+	TransientMultipleAuthChecksFailed                 BounceReason = "4.7.26"
+	TransientSenderAddressHasNullMX                   BounceReason = "4.7.27"
+	TransientARCValidationFailure                     BounceReason = "4.7.29"
+	TransientRequireTLSSupportRequired                BounceReason = "4.7.30"
+
 	// NotFound means we did not found the reason in the email
 	NotFound BounceReason = ""
 )
 
-// StatusMap is a map indexed by bounce reason that returns an object with
-// its bounce type and whether it's an specific error or not (an enhanced)
-var StatusMap = map[BounceReason]struct {
+//go:generate go run gen_status.go
+
+// StatusInfo contains the bounce type of a reason and whether it's an specific
+// error or not (an enhanced)
+type StatusInfo struct {
 	Type     BounceType
 	Specific bool
-}{
-	ServiceNotAvailable:               {Soft, false},
-	MailActionNotTaken:                {Soft, false},
-	ActionAbortedErrorProcessing:      {Soft, false},
-	ActionAbortedInsufficientStorage:  {Soft, false},
-	CmdSyntaxError:                    {Hard, false},
-	ArgumentsSyntaxError:              {Hard, false},
-	CmdNotImplemented:                 {Hard, false},
-	BadCmdSequence:                    {Hard, false},
-	CmdParamNotImplemented:            {Hard, false},
-	MailboxUnavailable:                {Hard, false},
-	RecipientNotLocal:                 {Hard, false},
-	ActionAbortedExceededStorageAlloc: {Hard, false},
-	MailboxNameInvalid:                {Hard, false},
-	TransactionFailed:                 {Hard, false},
-
-	AddressDoesntExist:                       {Hard, true},
-	OtherAddressError:                        {Hard, true},
-	BadDestinationMailboxAddress:             {Hard, true},
-	BadDestinationSystemAddress:              {Hard, true},
-	BadDestinationMailboxAddressSyntax:       {Hard, true},
-	DestinationMailboxAmbiguous:              {Hard, true},
-	DestinationMailboxAddressInvalid:         {Hard, true},
-	MailboxMoved:                             {Hard, true},
-	BadSenderMailboxAddressSyntax:            {Hard, true},
-	BadSenderSystemAddress:                   {Hard, true},
-	UndefinedMailboxError:                    {Soft, true},
-	MailboxDisabled:                          {Soft, true},
-	MailboxFull:                              {Soft, true},
-	MessageLenExceedsLimit:                   {Hard, true},
-	MailingListExpansionProblem:              {Hard, true},
-	UndefinedMailSystemStatus:                {Hard, true},
-	MailSystemFull:                           {Soft, true},
-	SystemNotAcceptingNetworkMessages:        {Hard, true},
-	SystemNotCapableOfFeatures:               {Hard, true},
-	MessageTooBigForSystem:                   {Hard, true},
-	UndefinedNetworkStatus:                   {Hard, true},
-	NoAnswerFromHost:                         {Hard, true},
-	BadConnection:                            {Hard, true},
-	RoutingServerFailure:                     {Hard, true},
-	UnableToRoute:                            {Hard, true},
-	NetworkCongestion:                        {Soft, true},
-	RoutingLoopDetected:                      {Hard, true},
-	DeliveryTimeExpired:                      {Hard, true},
-	UndefinedProtocolStatus:                  {Hard, true},
-	InvalidCommand:                           {Hard, true},
-	SyntaxError:                              {Hard, true},
-	TooManyRecipients:                        {Soft, true},
-	InvalidCommandArguments:                  {Hard, true},
-	WrongProtocolVersion:                     {Hard, true},
-	UndefinedMediaError:                      {Hard, true},
-	MediaNotSupported:                        {Hard, true},
-	ConversionRequiredAndProhibited:          {Hard, true},
-	ConversionRequiredButNotSupported:        {Hard, true},
-	ConversionWithLossPerformed:              {Hard, true},
-	ConversionFailed:                         {Hard, true},
-	UndefinedSecurityStatus:                  {Hard, true},
-	MessageRefused:                           {Hard, true},
-	MailingListExpansionProhibited:           {Hard, true},
-	SecurityConversionRequiredButNotPossible: {Hard, true},
-	SecurityFeaturesNotSupported:             {Hard, true},
-	CryptoFailure:                            {Hard, true},
-	CryptoAlgorithmNotSupported:              {Hard, true},
-	MessageIntegrityFailure:                  {Hard, true},
-	UndefinedCode:                            {Hard, true},
-}
-
-var reasonDescriptions = map[BounceReason]string{
-	ServiceNotAvailable:               "service not available",
-	MailActionNotTaken:                "mail action not taken: mailbox unavailable",
-	ActionAbortedErrorProcessing:      "action aborted: error in processing",
-	ActionAbortedInsufficientStorage:  "action aborted: insufficient system storage",
-	CmdSyntaxError:                    "the server could not recognize the command due to a syntax error",
-	ArgumentsSyntaxError:              "a syntax error was encountered in command arguments",
-	CmdNotImplemented:                 "this command is not implemented",
-	BadCmdSequence:                    "the server has encountered a bad sequence of commands",
-	CmdParamNotImplemented:            "a command parameter is not implemented",
-	MailboxUnavailable:                "user's mailbox was unavailable (such as not found)",
-	RecipientNotLocal:                 "the recipient is not local to the server",
-	ActionAbortedExceededStorageAlloc: "the action was aborted due to exceeded storage allocation",
-	MailboxNameInvalid:                "the command was aborted because the mailbox name is invalid",
-	TransactionFailed:                 "the transaction failed for some unstated reason",
-
-	AddressDoesntExist:                       "address does not exist",
-	OtherAddressError:                        "other address status",
-	BadDestinationMailboxAddress:             "bad destination mailbox address",
-	BadDestinationSystemAddress:              "bad destination system address",
-	BadDestinationMailboxAddressSyntax:       "bad destunation mailbox address syntax",
-	DestinationMailboxAmbiguous:              "destination mailbox address ambiguous",
-	DestinationMailboxAddressInvalid:         "destination mailbox address invalid",
-	MailboxMoved:                             "mailbox has moved",
-	BadSenderMailboxAddressSyntax:            "bad sender's mailbox address syntax",
-	BadSenderSystemAddress:                   "bad sender's system address",
-	UndefinedMailboxError:                    "other or undefined mailbox status",
-	MailboxDisabled:                          "mailbox disabled, not accepting messages",
-	MailboxFull:                              "mailbox full",
-	MessageLenExceedsLimit:                   "message length exceeds administrative limit",
-	MailingListExpansionProblem:              "mailing list expansion problem",
-	UndefinedMailSystemStatus:                "other or undefined mail system status",
-	MailSystemFull:                           "mail system full",
-	SystemNotAcceptingNetworkMessages:        "system not accepting network messages",
-	SystemNotCapableOfFeatures:               "system not capable of selected features",
-	MessageTooBigForSystem:                   "message too big for system",
-	UndefinedNetworkStatus:                   "other or undefined network or routing status",
-	NoAnswerFromHost:                         "no answer from host",
-	BadConnection:                            "bad connection",
-	RoutingServerFailure:                     "routing server failure",
-	UnableToRoute:                            "unable to route",
-	NetworkCongestion:                        "network congestion",
-	RoutingLoopDetected:                      "routing loop detected",
-	DeliveryTimeExpired:                      "delivery time expired",
-	UndefinedProtocolStatus:                  "other or undefined protocol status",
-	InvalidCommand:                           "invalid command",
-	SyntaxError:                              "syntax error",
-	TooManyRecipients:                        "too many recipients",
-	InvalidCommandArguments:                  "invalid command arguments",
-	WrongProtocolVersion:                     "wrong protocol version",
-	UndefinedMediaError:                      "other or undefined media error",
-	MediaNotSupported:                        "media not supported",
-	ConversionRequiredAndProhibited:          "conversion required and prohibited",
-	ConversionRequiredButNotSupported:        "conversion required but not supported",
-	ConversionWithLossPerformed:              "conversion with loss performed",
-	ConversionFailed:                         "conversion failed",
-	UndefinedSecurityStatus:                  "other or undefined security status",
-	MessageRefused:                           "delivery not authorized, message refused",
-	MailingListExpansionProhibited:           "mailing list expansion prohibited",
-	SecurityConversionRequiredButNotPossible: "security conversion required but nor possible",
-	SecurityFeaturesNotSupported:             "security features not supported",
-	CryptoFailure:                            "cryptographic failure",
-	CryptoAlgorithmNotSupported:              "cryptographic algorithm not supported",
-	MessageIntegrityFailure:                  "message integrity failure",
-	UndefinedCode:                            "hard bounce with no bounce code found",
 }
 
 const (
@@ -248,8 +268,8 @@ func (r BounceReason) Compare(o BounceReason) int {
 		return BothNotFound
 	}
 
-	infoSelf := StatusMap[r]
-	infoOther := StatusMap[o]
+	infoSelf := statusInfo(r)
+	infoOther := statusInfo(o)
 	if infoSelf.Specific == infoOther.Specific {
 		return Equal
 	} else if infoSelf.Specific {
@@ -269,29 +289,78 @@ func (r BounceReason) String() string {
 	return fmt.Sprintf(
 		"%s - %s",
 		string(r),
-		reasonDescriptions[r],
+		reasonDescriptions[knownReason(r)],
 	)
 }
 
+// Reference returns the document defining the status code of the reason,
+// such as "RFC3463", or an empty string if there is none
+func (r BounceReason) Reference() string {
+	return reasonReferences[knownReason(r)]
+}
+
 // Result is the returned value of the analysis. It contains the bounce type, the reason,
-// and the spam score if it was present.
+// the category, the recipient it refers to and the spam score if it was present.
+// Its JSON encoding is stable and documented in the README.
 type Result struct {
-	Type      BounceType
-	Reason    BounceReason
-	SpamScore float64
+	// Type is the type of the bounce. It's Unknown if no reason was found.
+	Type   BounceType   `json:"type"`
+	Reason BounceReason `json:"reason"`
+	// Action is the action reported for the recipient, such as Failed or
+	// Delayed, if it's known.
+	Action Action `json:"action,omitempty"`
+	// Category is the category given by a rule or the one of the reason and
+	// diagnostic of the bounce, see Categorize. It's CategoryUnknown for
+	// messages that are not bounces.
+	Category  Category `json:"category"`
+	Recipient string   `json:"recipient,omitempty"`
+	// Blocklist is the blocklist that rejected the message, if any, see
+	// ParseBlocklist.
+	Blocklist *Blocklist `json:"blocklist,omitempty"`
+	// Confidence is a value between 0 and 1 that tells how sure the detector
+	// that found the reason is about it. It's 0 if no reason was found.
+	Confidence float64 `json:"confidence"`
+	// Evidence tells where the reason was found. It's nil if no reason was
+	// found.
+	Evidence *Evidence `json:"evidence,omitempty"`
+	// Candidates are all the candidates found by the detectors of the
+	// analyzer, including the ones that were not used, in the order they
+	// were found.
+	Candidates []Candidate `json:"candidates,omitempty"`
+	SpamScore  float64     `json:"spam_score"`
+	// Spam contains the spam scores found in the headers of the message and
+	// of the original message.
+	Spam SpamReport `json:"spam"`
+	// Kind is the kind of the message. If it's not a Bounce, no bounce
+	// reason is looked for.
+	Kind MessageKind `json:"kind"`
+	// Feedback is the feedback report of complaints.
+	Feedback *FeedbackReport `json:"feedback,omitempty"`
+	// Original is the message the bounce or complaint is about, if it's
+	// included in the message.
+	Original *OriginalMessage `json:"original,omitempty"`
+	// Signature is the status of the signature of the return path the
+	// message was sent to. It's only checked by analyzers that verify
+	// signatures, see Analyzer.VerifySignatures.
+	Signature SignatureStatus `json:"signature"`
+	// Language is the BCP 47 tag of the language of the bounce message, if
+	// it's one of the supported ones.
+	Language string `json:"language,omitempty"`
 }
 
-// Analyze returns a Result given the headers and body of an email message
+// Analyze returns a Result given the headers and body of an email message.
+// If the message reports several recipients, the result is the one of the
+// recipient with the worst bounce, that is, a hard bounce over a soft one and
+// a more specific reason over a less specific one.
+// Only the decoded human-readable and delivery status parts of the message
+// are analyzed.
 func Analyze(headers mail.Header, body []byte) Result {
-	reason := FindBounceReason(body)
-	return Result{
-		SpamScore: SpamScore(headers),
-		Reason:    reason,
-		Type:      StatusMap[reason].Type,
-	}
+	return defaultAnalyzer.Analyze(NewMessage(headers, body))
 }
 
-// SpamScore finds the spam score given the email headers
+// SpamScore finds the spam score given the email headers. It only uses the
+// X-Spam-Score header and returns 0 if it's not present, see ParseSpamReport
+// for the scores of other headers.
 func SpamScore(headers mail.Header) float64 {
 	score := headers.Get("X-Spam-Score")
 	if score == "" {
@@ -316,46 +385,165 @@ const (
 
 // FindBounceReason returns the bounce reason found in the body of the email if it was found
 func FindBounceReason(body []byte) BounceReason {
-	lns := strings.Split(strings.ToLower(string(body)), "\n")
-	numLines := len(lns)
-
-	// we need to reverse the lines because some servers send a bounce email
-	// with a more specific error code in the end of the message and a less
-	// specific error at the beginning
-	var lines = make([]string, numLines)
-	for i, ln := range lns {
-		lines[numLines-i-1] = ln
+	var s reasonScanner
+	for {
+		idx := bytes.IndexByte(body, '\n')
+		if idx < 0 {
+			s.scan(body)
+			return s.finish()
+		}
+
+		s.scan(body[:idx])
+		body = body[idx+1:]
 	}
+}
 
-	for i, line := range lines {
-		if strings.HasPrefix(line, deliveryDelayed) {
-			return ServiceNotAvailable
+// maxLineLength is the number of bytes of each line kept by ReadBounceReason.
+// Longer lines are truncated, as no line with a reason is that long.
+const maxLineLength = 4096
+
+// ReadBounceReason returns the bounce reason found in the body of the email
+// read from r, just like FindBounceReason, without keeping more than a couple
+// of lines of the body in memory. It's meant for bounces that are too large to
+// be read at once, such as the ones that carry the original message with its
+// attachments.
+func ReadBounceReason(r io.Reader) (BounceReason, error) {
+	br := bufio.NewReaderSize(r, maxLineLength)
+	// the scanner keeps the previous line, so the lines are read into two
+	// buffers that are swapped on every line
+	var s reasonScanner
+	var line, prev []byte
+	for {
+		var err error
+		line, err = readLine(br, line[:0])
+		if err != nil && err != io.EOF {
+			return NotFound, err
 		}
 
-		if strings.HasPrefix(line, deliveryFailedPermanently) {
-			return UndefinedCode
+		s.scan(line)
+		if err == io.EOF {
+			return s.finish(), nil
 		}
 
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "status:") {
-			if reason := analyzeLine(line[6:]); reason != NotFound {
-				return reason
-			}
+		line, prev = prev, line
+	}
+}
+
+// readLine appends to buf the next line of r without its line break,
+// truncated to maxLineLength bytes. It returns io.EOF along with the last
+// line, which may be empty.
+func readLine(r *bufio.Reader, buf []byte) ([]byte, error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
 		}
 
-		if (strings.HasSuffix(line, reasonOfTheProblem) ||
-			strings.HasSuffix(line, reasonForTheProblem) ||
-			strings.HasSuffix(line, errorOtherServerReturned)) && i-1 >= 0 {
-			if reason := analyzeLine(lines[i-1]); reason != NotFound {
-				return reason
-			}
+		if n := maxLineLength - len(buf); len(chunk) > n {
+			chunk = chunk[:n]
+		}
+		buf = append(buf, chunk...)
+
+		if err != bufio.ErrBufferFull {
+			return buf, err
 		}
 	}
+}
 
-	return NotFound
+// reasonScanner finds the bounce reason of the lines of a body given one by
+// one. Some servers send a bounce email with a more specific error code in the
+// end of the message and a less specific error at the beginning, so the
+// reason found in the last line wins. As the reason of some lines is in the
+// line after them, each line is checked once the next one is given, which
+// must not be modified until then.
+type reasonScanner struct {
+	prev    []byte
+	hasPrev bool
+	reason  BounceReason
+}
+
+func (s *reasonScanner) scan(line []byte) {
+	if s.hasPrev {
+		s.check(s.prev, line, true)
+	}
+	s.prev, s.hasPrev = line, true
+}
+
+// finish checks the last line and returns the reason found
+func (s *reasonScanner) finish() BounceReason {
+	if s.hasPrev {
+		s.check(s.prev, nil, false)
+	}
+	return s.reason
+}
+
+func (s *reasonScanner) check(line, next []byte, hasNext bool) {
+	if hasPrefixFold(line, deliveryDelayed) {
+		s.reason = ServiceNotAvailable
+		return
+	}
+
+	if hasPrefixFold(line, deliveryFailedPermanently) {
+		s.reason = UndefinedCode
+		return
+	}
+
+	// lines are only copied and lowercased to be analyzed if they may have
+	// a reason, so most lines of large bodies are never allocated
+	line = bytes.TrimSpace(line)
+	if hasPrefixFold(line, "status:") {
+		if reason := analyzeLine(strings.ToLower(string(line[6:]))); reason != NotFound {
+			s.reason = reason
+			return
+		}
+	}
+
+	if (hasSuffixFold(line, reasonOfTheProblem) ||
+		hasSuffixFold(line, reasonForTheProblem) ||
+		hasSuffixFold(line, errorOtherServerReturned)) && hasNext {
+		if reason := analyzeLine(strings.ToLower(string(next))); reason != NotFound {
+			s.reason = reason
+		}
+	}
+}
+
+// hasPrefixFold reports whether b begins with the given lowercase ASCII
+// prefix, ignoring the case of b
+func hasPrefixFold(b []byte, prefix string) bool {
+	return len(b) >= len(prefix) && equalFoldASCII(b[:len(prefix)], prefix)
+}
+
+// hasSuffixFold reports whether b ends with the given lowercase ASCII suffix,
+// ignoring the case of b
+func hasSuffixFold(b []byte, suffix string) bool {
+	return len(b) >= len(suffix) && equalFoldASCII(b[len(b)-len(suffix):], suffix)
+}
+
+func equalFoldASCII(b []byte, lower string) bool {
+	for i := 0; i < len(lower); i++ {
+		c := b[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		if c != lower[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
 func analyzeLine(line string) BounceReason {
+	reason, _ := lineReasons(line)
+	return reason
+}
+
+// lineReasons returns the reason analyzeLine finds in the line and the one of
+// the other status code it was compared with, if any, so the reasons that
+// were considered can be audited. The other reason is NotFound if the line
+// has a single status code.
+func lineReasons(line string) (reason, competing BounceReason) {
 	var firstStatus, secondStatus BounceReason
 	parts := strings.Split(removeUnnecessaryChars(line), " ")
 
@@ -369,19 +557,32 @@ func analyzeLine(line string) BounceReason {
 
 	switch firstStatus.Compare(secondStatus) {
 	case LessSpecific:
-		return secondStatus
-	case MoreSpecific, Equal:
-		return firstStatus
+		return secondStatus, firstStatus
+	case MoreSpecific:
+		return firstStatus, secondStatus
+	case Equal:
+		return firstStatus, NotFound
 	default:
-		return NotFound
+		return NotFound, NotFound
 	}
 }
 
+// statusPunctuation are the characters that may surround a status code in a
+// line, such as "(556)", "<5.1.1>" or "535:"
+const statusPunctuation = "()[]<>.,:;'\""
+
 func parseStatus(status string) BounceReason {
-	status = strings.TrimSpace(status)
+	status = strings.Trim(strings.TrimSpace(status), statusPunctuation)
 	if _, ok := StatusMap[BounceReason(status)]; ok {
 		return BounceReason(status)
 	}
+
+	// enhanced status codes that are not in the StatusMap are kept as they
+	// are as long as there is a less specific code known for them
+	if code, err := ParseEnhancedStatusCode(status); err == nil && code.KnownReason() != NotFound {
+		return code.Reason()
+	}
+
 	return NotFound
 }
 
@@ -1,8 +1,12 @@
 package bouncespy
 
 import (
+	"bytes"
 	"net/mail"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	ch "gopkg.in/check.v1"
 )
@@ -32,6 +36,7 @@ func (s *BounceSuite) TestBounceReasonCompare(c *ch.C) {
 	}{
 		{NotFound, NotFound, BothNotFound},
 		{ServiceNotAvailable, CryptoFailure, LessSpecific},
+		{ServiceNotAvailable, TransientMailboxFull, LessSpecific},
 		{NotFound, CryptoFailure, LessSpecific},
 		{MailboxUnavailable, MailboxUnavailable, Equal},
 		{CryptoFailure, CryptoFailure, Equal},
@@ -57,6 +62,23 @@ func (s *BounceSuite) TestAnalyzeLine(c *ch.C) {
 		{"421 (a ksk sogjsdhvkfg dk)", ServiceNotAvailable},
 		{"5.0.0 (a ksk sogjsdhvkfg dk)", AddressDoesntExist},
 		{"5.0.0- a ksk sogjsdhvkfg dk", AddressDoesntExist},
+		{"452 4.2.2 mailbox full", TransientMailboxFull},
+		{"451-4.7.1 greylisted, please try again later", TransientMessageRefused},
+		{"4.4.7 (delivery time expired)", TransientDeliveryTimeExpired},
+		{"556 5.1.10 <foo@foo.foo>: recipient address has null mx", RecipientAddressHasNullMX},
+		{"521 foo.foo does not accept mail", HostNotAcceptingMail},
+		{"556 domain does not accept mail", DomainNotAcceptingMail},
+		{"530-5.7.0 authentication required", UndefinedSecurityStatus},
+		{"535 authentication credentials invalid", AuthInvalidCredentials},
+		{"534 5.7.9 authentication mechanism is too weak", AuthMechanismTooWeak},
+		{"538 encryption required", AuthEncryptionRequired},
+		{"454 4.7.0 tls not available due to local problem", TransientUndefinedSecurityStatus},
+		{"432 4.7.12 a password transition is needed", TransientPasswordTransitionNeeded},
+		{"455 server unable to accommodate parameters", ParamsNotAccommodated},
+		{"555 5.5.4 unsupported option: foo", InvalidCommandArguments},
+		{"(556) domain does not accept mail", DomainNotAcceptingMail},
+		{"550: <5.1.1> user unknown", BadDestinationMailboxAddress},
+		{"5.7.26. unauthenticated email is not accepted", MultipleAuthChecksFailed},
 		{"a ksk sogjsdhvkfg dk", NotFound},
 	}
 
@@ -125,3 +147,164 @@ func (s *BounceSuite) TestFindBounceReason(c *ch.C) {
 		c.Assert(FindBounceReason([]byte(cs.msg)), Equals, cs.r)
 	}
 }
+
+func (s *BounceSuite) TestReadBounceReason(c *ch.C) {
+	cases := []string{msg1, msg2, msg3, msg4, msg5, msg6, msg7, msg8, largeBounce(1 << 20)}
+
+	for i, msg := range cases {
+		comment := ch.Commentf("message %d", i)
+		expected := FindBounceReason([]byte(msg))
+
+		reason, err := ReadBounceReason(strings.NewReader(msg))
+		c.Assert(err, ch.IsNil, comment)
+		c.Assert(reason, Equals, expected, comment)
+
+		reason, err = ReadBounceReason(iotest.OneByteReader(strings.NewReader(msg)))
+		c.Assert(err, ch.IsNil, comment)
+		c.Assert(reason, Equals, expected, comment)
+	}
+
+	c.Assert(FindBounceReason([]byte(largeBounce(1<<20))), Equals, BadDestinationMailboxAddress)
+
+	// the reason of a line is in the next one, even if the line before it
+	// is longer than the lines that are kept
+	long := strings.Repeat("a", 3*maxLineLength) + "\nThe reason for the problem:\n5.1.0 - Unknown address error"
+	reason, err := ReadBounceReason(strings.NewReader(long))
+	c.Assert(err, ch.IsNil)
+	c.Assert(reason, Equals, OtherAddressError)
+
+	_, err = ReadBounceReason(iotest.TimeoutReader(iotest.HalfReader(strings.NewReader(msg2))))
+	c.Assert(err, Equals, iotest.ErrTimeout)
+}
+
+// largeBounce returns a bounce that carries an attachment of the given size
+// encoded in base64, with a less specific reason before the attachment and
+// the most specific one after it
+func largeBounce(size int) string {
+	var buf bytes.Buffer
+	buf.WriteString(msg1)
+	buf.WriteString("\r\n\r\nContent-Type: application/octet-stream\r\nContent-Transfer-Encoding: base64\r\n\r\n")
+
+	line := strings.Repeat("QUJDRA", 76/6) + "QUJD\r\n"
+	for buf.Len() < size {
+		buf.WriteString(line)
+	}
+
+	buf.WriteString("\r\nFinal-Recipient: rfc822;foo@foo.foo\r\nAction: failed\r\nStatus: 5.1.1\r\n")
+	return buf.String()
+}
+
+func BenchmarkFindBounceReason(b *testing.B) {
+	benchmarkBounceReason(b, func(msg []byte) BounceReason {
+		return FindBounceReason(msg)
+	})
+}
+
+func BenchmarkReadBounceReason(b *testing.B) {
+	benchmarkBounceReason(b, func(msg []byte) BounceReason {
+		reason, _ := ReadBounceReason(bytes.NewReader(msg))
+		return reason
+	})
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	benchmarkBounceReason(b, func(msg []byte) BounceReason {
+		return Analyze(mail.Header{}, msg).Reason
+	})
+}
+
+func benchmarkBounceReason(b *testing.B, find func([]byte) BounceReason) {
+	for _, size := range []int{1 << 10, 1 << 20, 20 << 20} {
+		msg := []byte(largeBounce(size))
+		b.Run(strconv.Itoa(size>>10)+"KB", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(msg)))
+			for i := 0; i < b.N; i++ {
+				if find(msg) != BadDestinationMailboxAddress {
+					b.Fatal("reason not found")
+				}
+			}
+		})
+	}
+}
+
+func (s *BounceSuite) TestBounceReasonString(c *ch.C) {
+	cases := []struct {
+		r   BounceReason
+		str string
+	}{
+		{NotFound, "no bounce reason found"},
+		{MailboxUnavailable, "550 - user's mailbox was unavailable (such as not found)"},
+		{BadDestinationMailboxAddress, "5.1.1 - bad destination mailbox address"},
+		{TransientMailboxFull, "4.2.2 - mailbox full"},
+		{SPFValidationFailed, "5.7.23 - SPF validation failed"},
+		{NonASCIIAddressesNotPermitted, "5.6.7 - non-ASCII addresses not permitted for that sender/recipient"},
+		{UndefinedCode, "9.1.1 - hard bounce with no bounce code found"},
+	}
+
+	for _, cs := range cases {
+		c.Assert(cs.r.String(), Equals, cs.str)
+	}
+}
+
+func (s *BounceSuite) TestBounceReasonReference(c *ch.C) {
+	cases := []struct {
+		r   BounceReason
+		ref string
+	}{
+		{NotFound, ""},
+		{UndefinedCode, ""},
+		{MailboxUnavailable, "RFC5321"},
+		{BadDestinationMailboxAddress, "RFC3463"},
+		{AuthCredentialsInvalid, "RFC4954"},
+		{TransientSPFValidationError, "RFC7372"},
+		{RecipientAddressHasNullMX, "RFC7505"},
+		{BounceReason("5.1.99"), "RFC3463"},
+	}
+
+	for _, cs := range cases {
+		c.Assert(cs.r.Reference(), Equals, cs.ref)
+	}
+}
+
+func (s *BounceSuite) TestStatusMapTypes(c *ch.C) {
+	for reason, info := range StatusMap {
+		if strings.HasPrefix(string(reason), "4") {
+			c.Assert(info.Type, Equals, Soft, ch.Commentf("reason %s", reason))
+		}
+		c.Assert(reasonDescriptions[reason], ch.Not(Equals), "", ch.Commentf("reason %s", reason))
+	}
+
+	c.Assert(StatusMap[UserAccountDisabled], Equals, StatusInfo{Hard, true})
+	c.Assert(StatusMap[MailboxFull], Equals, StatusInfo{Soft, true})
+	c.Assert(StatusMap[TransientAuthCredentialsInvalid], Equals, StatusInfo{Soft, true})
+	c.Assert(StatusMap[DomainNotAcceptingMail], Equals, StatusInfo{Hard, false})
+	c.Assert(StatusMap[HostNotAcceptingMail], Equals, StatusInfo{Hard, false})
+	c.Assert(StatusMap[AuthRequired], Equals, StatusInfo{Hard, false})
+	c.Assert(StatusMap[TemporaryAuthFailure], Equals, StatusInfo{Soft, false})
+
+	// positive replies are not bounces
+	for _, reason := range []BounceReason{"251", "252"} {
+		_, ok := StatusMap[reason]
+		c.Assert(ok, Equals, false, ch.Commentf("reason %s", reason))
+		c.Assert(statusInfo(reason), Equals, StatusInfo{Type: Unknown})
+	}
+	c.Assert(analyzeLine("252 cannot verify the user, but will accept the message"), Equals, NotFound)
+}
+
+func (s *BounceSuite) TestBounceType(c *ch.C) {
+	c.Assert(Hard.String(), Equals, "hard")
+	c.Assert(Unknown.String(), Equals, "unknown")
+
+	typ, err := parseBounceType(" Unknown")
+	c.Assert(err, ch.IsNil)
+	c.Assert(typ, Equals, Unknown)
+
+	_, err = parseBounceType("medium")
+	c.Assert(err, ch.NotNil)
+
+	c.Assert(statusInfo(NotFound).Type, Equals, Unknown)
+	c.Assert(statusInfo("9.9.9").Type, Equals, Unknown)
+	c.Assert(statusInfo(MailboxFull).Type, Equals, Soft)
+	c.Assert(statusInfo(BadDestinationMailboxAddress).Type, Equals, Hard)
+}
@@ -0,0 +1,269 @@
+package bouncespy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Category is a stable classification of the cause of a bounce that tells
+// what to do about it, such as suppressing the address, retrying later or
+// fixing the authentication of the sender.
+type Category string
+
+const (
+	CategoryBadMailbox      Category = "bad_mailbox"
+	CategoryInactiveMailbox Category = "inactive_mailbox"
+	CategoryMailboxFull     Category = "mailbox_full"
+	CategoryDomainNotFound  Category = "domain_not_found"
+	CategoryRouting         Category = "routing"
+	CategoryPolicy          Category = "policy"
+	CategorySpam            Category = "spam"
+	CategoryReputation      Category = "reputation"
+	// CategoryAuthentication is the category of failed SPF, DKIM or DMARC
+	// checks and of failed SMTP authentication.
+	CategoryAuthentication  Category = "authentication"
+	CategoryRateLimited     Category = "rate_limited"
+	CategoryGreylisted      Category = "greylisted"
+	CategoryMessageTooLarge Category = "message_too_large"
+	CategoryVirus           Category = "virus"
+	CategoryTLSRequired     Category = "tls_required"
+	CategoryProtocol        Category = "protocol"
+	CategoryUnknown         Category = "unknown"
+)
+
+var categories = []Category{
+	CategoryBadMailbox,
+	CategoryInactiveMailbox,
+	CategoryMailboxFull,
+	CategoryDomainNotFound,
+	CategoryRouting,
+	CategoryPolicy,
+	CategorySpam,
+	CategoryReputation,
+	CategoryAuthentication,
+	CategoryRateLimited,
+	CategoryGreylisted,
+	CategoryMessageTooLarge,
+	CategoryVirus,
+	CategoryTLSRequired,
+	CategoryProtocol,
+	CategoryUnknown,
+}
+
+func parseCategory(s string) (Category, error) {
+	for _, c := range categories {
+		if strings.EqualFold(strings.TrimSpace(s), string(c)) {
+			return c, nil
+		}
+	}
+
+	return CategoryUnknown, fmt.Errorf("bouncespy: invalid category %q", s)
+}
+
+// categoryPhrases are the lowercase phrases of the diagnostics of every
+// category in the order they are looked for, so the most specific causes,
+// such as a blocklist that mentions spam, come first. Phrases are matched as
+// whole words, so the inflected forms of the words are listed too. The blocklists known by
+// ParseBlocklist are looked for before any of them.
+var categoryPhrases = []struct {
+	category Category
+	phrases  []string
+}{
+	{CategoryGreylisted, []string{
+		"greylist", "graylist", "greylisted", "graylisted", "greylisting",
+		"graylisting", "grey list", "gray list", "grey listed", "gray listed",
+	}},
+	{CategoryVirus, []string{"virus", "viruses", "malware", "infected"}},
+	{CategoryReputation, []string{
+		"blocklist", "blacklist", "blocklisted", "blacklisted", "block list",
+		"black list", "block listed", "black listed", "dnsbl", "reputation",
+	}},
+	{CategoryAuthentication, []string{"spf", "dkim", "dmarc", "authentication failed", "authentication required"}},
+	{CategoryTLSRequired, []string{"starttls", "tls required", "requires tls", "tls is required", "encryption required"}},
+	{CategoryRateLimited, []string{
+		"rate limit", "rate-limit", "ratelimit", "rate limited", "rate-limited",
+		"ratelimited", "throttle", "throttled", "throttling", "unusual rate",
+		"sending rate", "too many messages", "too many connections",
+	}},
+	{CategorySpam, []string{"spam", "junk mail", "bulk mail"}},
+	{CategoryMessageTooLarge, []string{
+		"too large", "too big", "size limit", "exceeds the maximum size",
+		"message size exceeds",
+	}},
+	{CategoryMailboxFull, []string{
+		"mailbox full", "mailbox is full", "quota", "insufficient storage",
+		"exceeded storage",
+	}},
+	{CategoryInactiveMailbox, []string{
+		"disabled", "inactive", "suspended", "deactivated", "discontinued",
+		"no longer active", "account expired",
+	}},
+	{CategoryDomainNotFound, []string{
+		"domain not found", "no such domain", "domain does not exist",
+		"domain name not found", "host not found", "host unknown", "nxdomain",
+		"name or service not known", "no mx record",
+	}},
+	{CategoryBadMailbox, []string{
+		"user unknown", "unknown user", "no such user", "no such mailbox",
+		"unknown recipient", "recipient unknown", "invalid recipient",
+		"mailbox not found", "mailbox unavailable", "does not exist",
+		"wasn't found", "address rejected",
+	}},
+	{CategoryRouting, []string{
+		"connection refused", "connection timed out", "mail loop",
+		"routing loop", "too many hops",
+	}},
+	{CategoryPolicy, []string{"policy", "policies"}},
+}
+
+// enhancedCategories are the categories of the enhanced status codes by their
+// subject and detail, regardless of their class. The categories by subject
+// are used for the details not in the map.
+var enhancedCategories = map[string]Category{
+	"1":    CategoryBadMailbox,
+	"1.2":  CategoryDomainNotFound,
+	"1.6":  CategoryInactiveMailbox,
+	"1.7":  CategoryPolicy,
+	"1.8":  CategoryPolicy,
+	"1.9":  CategoryProtocol,
+	"1.10": CategoryDomainNotFound,
+	"2":    CategoryInactiveMailbox,
+	"2.2":  CategoryMailboxFull,
+	"2.3":  CategoryMessageTooLarge,
+	"2.4":  CategoryRouting,
+	"3":    CategoryRouting,
+	"3.1":  CategoryMailboxFull,
+	"3.3":  CategoryProtocol,
+	"3.4":  CategoryMessageTooLarge,
+	"3.6":  CategoryPolicy,
+	"4":    CategoryRouting,
+	"4.5":  CategoryRateLimited,
+	"5":    CategoryProtocol,
+	"5.3":  CategoryRateLimited,
+	"6":    CategoryPolicy,
+	"7":    CategoryPolicy,
+	"7.4":  CategoryTLSRequired,
+	"7.8":  CategoryAuthentication,
+	"7.9":  CategoryAuthentication,
+	"7.10": CategoryTLSRequired,
+	"7.11": CategoryTLSRequired,
+	"7.12": CategoryAuthentication,
+	"7.13": CategoryInactiveMailbox,
+	"7.14": CategoryAuthentication,
+	"7.17": CategoryInactiveMailbox,
+	"7.18": CategoryInactiveMailbox,
+	"7.20": CategoryAuthentication,
+	"7.21": CategoryAuthentication,
+	"7.22": CategoryAuthentication,
+	"7.23": CategoryAuthentication,
+	"7.24": CategoryAuthentication,
+	"7.25": CategoryAuthentication,
+	"7.26": CategoryAuthentication,
+	"7.29": CategoryAuthentication,
+	"7.30": CategoryTLSRequired,
+}
+
+// basicCategories are the categories of the basic SMTP reply codes
+var basicCategories = map[BounceReason]Category{
+	PasswordTransitionRequired:        CategoryAuthentication,
+	ActionAbortedInsufficientStorage:  CategoryMailboxFull,
+	TemporaryAuthFailure:              CategoryAuthentication,
+	ParamsNotAccommodated:             CategoryProtocol,
+	CmdSyntaxError:                    CategoryProtocol,
+	ArgumentsSyntaxError:              CategoryProtocol,
+	CmdNotImplemented:                 CategoryProtocol,
+	BadCmdSequence:                    CategoryProtocol,
+	CmdParamNotImplemented:            CategoryProtocol,
+	HostNotAcceptingMail:              CategoryDomainNotFound,
+	AuthRequired:                      CategoryAuthentication,
+	AuthMechTooWeak:                   CategoryAuthentication,
+	AuthInvalidCredentials:            CategoryAuthentication,
+	AuthEncryptionRequired:            CategoryTLSRequired,
+	MailboxUnavailable:                CategoryBadMailbox,
+	RecipientNotLocal:                 CategoryBadMailbox,
+	ActionAbortedExceededStorageAlloc: CategoryMailboxFull,
+	MailboxNameInvalid:                CategoryBadMailbox,
+	TransactionFailed:                 CategoryPolicy,
+	MailRcptParamsNotImplemented:      CategoryProtocol,
+	DomainNotAcceptingMail:            CategoryDomainNotFound,
+}
+
+// Categorize returns the category of a bounce with the given reason and
+// diagnostic. The addressing and mailbox full status codes, X.1.x and X.2.2,
+// are specific enough to be used first. Otherwise the phrases of the
+// diagnostic are used, as they tell apart causes that share a status code,
+// such as a spam block and a sender in a blocklist, and the status code is
+// used if none of them is found. CategoryUnknown is returned if neither of
+// them is known.
+func Categorize(reason BounceReason, diagnostic string) Category {
+	code, err := ParseEnhancedStatusCode(string(reason))
+	if err == nil && (code.Subject == 1 || (code.Subject == 2 && code.Detail == 2)) {
+		return codeCategory(reason)
+	}
+
+	if ParseBlocklist(diagnostic) != nil {
+		return CategoryReputation
+	}
+
+	lower := strings.ToLower(diagnostic)
+	for _, p := range categoryPhrases {
+		if containsWords(lower, p.phrases) {
+			return p.category
+		}
+	}
+
+	return codeCategory(reason)
+}
+
+// codeCategory returns the category of the status code of the reason
+func codeCategory(reason BounceReason) Category {
+	code, err := ParseEnhancedStatusCode(string(reason))
+	if err != nil {
+		if c, ok := basicCategories[reason]; ok {
+			return c
+		}
+		return CategoryUnknown
+	}
+
+	if c, ok := enhancedCategories[strconv.Itoa(code.Subject)+"."+strconv.Itoa(code.Detail)]; ok {
+		return c
+	}
+
+	if c, ok := enhancedCategories[strconv.Itoa(code.Subject)]; ok {
+		return c
+	}
+
+	return CategoryUnknown
+}
+
+// containsWords reports whether the lowercase text contains any of the
+// phrases as whole words, so "spam" is not found in "spamhaus".
+func containsWords(s string, phrases []string) bool {
+	for _, p := range phrases {
+		for offset := 0; offset < len(s); {
+			idx := strings.Index(s[offset:], p)
+			if idx < 0 {
+				break
+			}
+
+			start, end := offset+idx, offset+idx+len(p)
+			before, _ := utf8.DecodeLastRuneInString(s[:start])
+			after, _ := utf8.DecodeRuneInString(s[end:])
+			if !isWordRune(before) && !isWordRune(after) {
+				return true
+			}
+			offset = start + 1
+		}
+	}
+
+	return false
+}
+
+// isWordRune reports whether the rune is part of a word. The RuneError
+// returned at the edges of a string is not.
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,88 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestCategorize(c *ch.C) {
+	cases := []struct {
+		reason     BounceReason
+		diagnostic string
+		category   Category
+	}{
+		{BadDestinationMailboxAddress, "", CategoryBadMailbox},
+		{TransientBadDestinationMailboxAddress, "", CategoryBadMailbox},
+		{BadDestinationSystemAddress, "", CategoryDomainNotFound},
+		{MailboxDisabled, "", CategoryInactiveMailbox},
+		{MailboxFull, "", CategoryMailboxFull},
+		{MessageLenExceedsLimit, "", CategoryMessageTooLarge},
+		{RoutingLoopDetected, "", CategoryRouting},
+		{InvalidCommand, "", CategoryProtocol},
+		{MessageRefused, "", CategoryPolicy},
+		{SPFValidationFailed, "", CategoryAuthentication},
+		{RequireTLSSupportRequired, "", CategoryTLSRequired},
+		{"5.7.500", "", CategoryPolicy},
+		{"4.9.9", "", CategoryUnknown},
+		{MailboxUnavailable, "", CategoryBadMailbox},
+		{ActionAbortedExceededStorageAlloc, "", CategoryMailboxFull},
+		{AuthEncryptionRequired, "", CategoryTLSRequired},
+		{ServiceNotAvailable, "", CategoryUnknown},
+		{AddressDoesntExist, "", CategoryUnknown},
+		{NotFound, "", CategoryUnknown},
+		{MessageRefused, "550 5.7.1 Message rejected as spam", CategorySpam},
+		{MessageRefused, "554 5.7.1 Service unavailable; Client host [1.1.1.1] blocked using zen.spamhaus.org", CategoryReputation},
+		{MessageRefused, "550 5.7.1 Unauthenticated email is not accepted due to the sender's DMARC policy", CategoryAuthentication},
+		{"4.2.0", "450 4.2.0 Recipient address rejected: Greylisted", CategoryGreylisted},
+		{"4.7.0", "421 4.7.0 Our system has detected an unusual rate of unsolicited mail", CategoryRateLimited},
+		{MediaNotSupported, "552 5.6.1 Message rejected, virus found", CategoryVirus},
+		{MailboxUnavailable, "550 Requested action not taken: mailbox unavailable (account disabled)", CategoryInactiveMailbox},
+		{NotFound, "Host or domain name not found. Name service error for name=foo.foo", CategoryDomainNotFound},
+		{UndefinedCode, "Remote host said: User unknown", CategoryBadMailbox},
+		{TransactionFailed, "554 Message size exceeds fixed limit: message is too large", CategoryMessageTooLarge},
+		{"4.2.0", "451 4.2.0 Sender throttled, try again later", CategoryRateLimited},
+		// the phrases are whole words and addressing and mailbox full codes
+		// are used before them
+		{"5.7.0", "554 5.7.0 Rejected by spamguard", CategoryPolicy},
+		{BadDestinationMailboxAddress, "550 5.1.1 user unknown, see https://www.spamhaus.org/faq", CategoryBadMailbox},
+		{TransientBadDestinationMailboxAddress, "450 4.1.1 recipient rejected as spam", CategoryBadMailbox},
+		{MailboxFull, "552 5.2.2 mailbox full, message looks like spam", CategoryMailboxFull},
+	}
+
+	for _, cs := range cases {
+		c.Assert(Categorize(cs.reason, cs.diagnostic), Equals, cs.category, ch.Commentf("reason: %s, diagnostic: %s", cs.reason, cs.diagnostic))
+	}
+}
+
+func (s *BounceSuite) TestParseCategory(c *ch.C) {
+	category, err := parseCategory(" Mailbox_Full ")
+	c.Assert(err, ch.IsNil)
+	c.Assert(category, Equals, CategoryMailboxFull)
+
+	_, err = parseCategory("full")
+	c.Assert(err, ch.NotNil)
+}
+
+func (s *BounceSuite) TestAnalyzeCategory(c *ch.C) {
+	cases := []struct {
+		msg      string
+		category Category
+	}{
+		{msg1, CategoryInactiveMailbox},
+		{msg3, CategoryBadMailbox},
+		{msg4, CategoryUnknown},
+		{qmailMsg, CategoryBadMailbox},
+		{postfixMsg, CategoryPolicy},
+	}
+
+	for _, cs := range cases {
+		result := Analyze(mail.Header{}, []byte(cs.msg))
+		c.Assert(result.Category, Equals, cs.category, ch.Commentf("msg: %s", cs.msg))
+	}
+
+	results := AnalyzeRecipients(mail.Header{}, []byte(qmailMsg))
+	c.Assert(results, ch.HasLen, 2)
+	c.Assert(results[0].Category, Equals, CategoryBadMailbox)
+	c.Assert(results[1].Category, Equals, CategoryMailboxFull)
+}
@@ -0,0 +1,89 @@
+package bouncespy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CharsetReader is used to decode the parts and encoded header words whose
+// charset is not known by the package. It works the same way as the
+// CharsetReader of mime.WordDecoder. If it is nil or returns an error, the
+// content is used as is.
+var CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+// charsetReader returns a reader that decodes the input from the given
+// charset to UTF-8.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if isUTF8(charset) {
+		return input, nil
+	}
+
+	switch charset {
+	case "utf-16":
+		// big endian is the default if there is no byte order mark according
+		// to https://tools.ietf.org/html/rfc2781#section-4.3
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder().Reader(input), nil
+	}
+
+	if enc, err := htmlindex.Get(charset); err == nil {
+		return enc.NewDecoder().Reader(input), nil
+	}
+
+	if CharsetReader != nil {
+		return CharsetReader(charset, input)
+	}
+
+	return nil, fmt.Errorf("bouncespy: unknown charset %q", charset)
+}
+
+// isUTF8 reports whether the lowercase charset is UTF-8 or a subset of it,
+// so its content does not need to be decoded
+func isUTF8(charset string) bool {
+	switch charset {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+
+	return false
+}
+
+// decodeCharset decodes the content from the given charset to UTF-8. If the
+// content cannot be decoded, or it's already UTF-8, it is returned as is.
+func decodeCharset(charset string, content []byte) []byte {
+	if isUTF8(strings.ToLower(strings.TrimSpace(charset))) {
+		return content
+	}
+
+	r, err := charsetReader(charset, bytes.NewReader(content))
+	if err != nil {
+		return content
+	}
+
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return content
+	}
+
+	return decoded
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// decodeHeader decodes the RFC 2047 encoded words in the header value. If the
+// value cannot be decoded it is returned as is.
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decoded
+}
@@ -0,0 +1,99 @@
+package bouncespy
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	ch "gopkg.in/check.v1"
+)
+
+func encodeString(c *ch.C, enc encoding.Encoding, s string) []byte {
+	encoded, err := enc.NewEncoder().Bytes([]byte(s))
+	c.Assert(err, ch.IsNil)
+	return encoded
+}
+
+func textMessage(charset string, body []byte) *Message {
+	h := mail.Header{"Content-Type": []string{"text/plain; charset=" + charset}}
+	return NewMessage(h, body)
+}
+
+func (s *BounceSuite) TestDecodeCharset(c *ch.C) {
+	cases := []struct {
+		charset string
+		enc     encoding.Encoding
+		text    string
+	}{
+		{"ISO-8859-1", charmap.ISO8859_1, "Die Nachricht konnte nicht zugestellt werden: Empfänger unbekannt"},
+		{"windows-1252", charmap.Windows1252, "Non remis : l’adresse n’existe pas"},
+		{"Shift_JIS", japanese.ShiftJIS, "メッセージを配信できませんでした"},
+		{"iso-2022-jp", japanese.ISO2022JP, "メッセージを配信できませんでした"},
+		{"koi8-r", charmap.KOI8R, "Сообщение не доставлено"},
+		{"utf-16", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "Status: 5.1.1"},
+		{"utf-16", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), "Status: 5.1.1"},
+		{"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), "Status: 5.1.1"},
+	}
+
+	for _, cs := range cases {
+		m := textMessage(cs.charset, encodeString(c, cs.enc, cs.text))
+		c.Assert(string(m.Parts[0].Body), Equals, cs.text, ch.Commentf("charset %s", cs.charset))
+	}
+}
+
+func (s *BounceSuite) TestDecodeCharsetUTF8(c *ch.C) {
+	for _, charset := range []string{"", "UTF-8", " us-ascii"} {
+		content := []byte("Status: 5.1.1")
+		decoded := decodeCharset(charset, content)
+		c.Assert(string(decoded), Equals, "Status: 5.1.1")
+		// the content is not copied
+		c.Assert(&decoded[0] == &content[0], Equals, true, ch.Commentf("charset %q", charset))
+	}
+}
+
+func (s *BounceSuite) TestDecodeCharsetUnknown(c *ch.C) {
+	m := textMessage("x-foo", []byte("Status: 5.1.1"))
+	c.Assert(string(m.Parts[0].Body), Equals, "Status: 5.1.1")
+
+	CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		c.Assert(charset, Equals, "x-foo")
+		content, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(bytes.ToUpper(content)), nil
+	}
+	defer func() { CharsetReader = nil }()
+
+	m = textMessage("x-foo", []byte("Status: 5.1.1"))
+	c.Assert(string(m.Parts[0].Body), Equals, "STATUS: 5.1.1")
+}
+
+func (s *BounceSuite) TestAnalyzeMessageCharset(c *ch.C) {
+	msg := "Content-Type: text/plain; charset=utf-16\r\n" +
+		"Content-Transfer-Encoding: binary\r\n\r\n" +
+		string(encodeString(c, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), msg2))
+
+	result, err := AnalyzeMessage(strings.NewReader(msg))
+	c.Assert(err, ch.IsNil)
+	c.Assert(result.Reason, Equals, AddressDoesntExist)
+}
+
+func (s *BounceSuite) TestHeaderValue(c *ch.C) {
+	m := NewMessage(mail.Header{
+		"Subject": []string{"=?ISO-8859-1?Q?Unzustellbar:_R=FCckmeldung?="},
+		"From":    []string{"=?shift_jis?B?g4GDYoNagVuDVw==?= <postmaster@foo.jp>"},
+		"To":      []string{"=?x-foo?Q?foo?= <foo@foo.foo>"},
+	}, nil)
+
+	c.Assert(m.HeaderValue("Subject"), Equals, "Unzustellbar: Rückmeldung")
+	c.Assert(m.HeaderValue("From"), Equals, "メッセージ <postmaster@foo.jp>")
+	c.Assert(m.HeaderValue("To"), Equals, "=?x-foo?Q?foo?= <foo@foo.foo>")
+	c.Assert(m.HeaderValue("X-Foo"), Equals, "")
+}
@@ -0,0 +1,259 @@
+package bouncespy
+
+import (
+	"mime"
+	"net/mail"
+	"strings"
+)
+
+// bounceThreshold is the minimum confidence for a message to be a bounce
+const bounceThreshold = 0.5
+
+// Names of the signals used to classify messages
+const (
+	SignalDeliveryStatus    = "delivery-status"
+	SignalFeedbackReport    = "feedback-report"
+	SignalXARF              = "x-arf"
+	SignalFailedRecipients  = "x-failed-recipients"
+	SignalExchangeNDR       = "exchange-ndr"
+	SignalMailerDaemon      = "mailer-daemon"
+	SignalStatusLine        = "status-line"
+	SignalQmailRecipient    = "qmail-recipient"
+	SignalNullReturnPath    = "null-return-path"
+	SignalAutoSubmitted     = "auto-submitted"
+	SignalAutoReplyHeader   = "auto-reply-header"
+	SignalPrecedence        = "precedence"
+	SignalExchangeRulesLoop = "exchange-rules-loop"
+	SignalChallengeHeader   = "challenge-header"
+	SignalInReplyTo         = "in-reply-to"
+	SignalSubject           = "subject"
+	SignalBody              = "body"
+)
+
+// Classification is the kind of a message and how sure the classifier is
+// about it.
+type Classification struct {
+	Kind MessageKind
+	// Confidence is a value between 0 and 1. Messages with no signals at all
+	// are classified as bounces with no confidence.
+	Confidence float64
+	// Signals are the names of the signals found for the kind.
+	Signals []string
+}
+
+// IsBounce reports whether the message is a bounce with enough confidence
+func (c Classification) IsBounce() bool {
+	return c.Kind == Bounce && c.Confidence >= bounceThreshold
+}
+
+// bounceSubjects are the lowercase phrases of the subjects of bounces, apart
+// from the notices of the phrase catalogue
+var bounceSubjects = []string{
+	"undelivered mail",
+	"mail delivery failed",
+	"mail delivery system",
+	"delivery failure",
+	"delivery has failed",
+	"failure notice",
+	"returned mail",
+	"delivery status notification (failure)",
+	"delivery status notification (delay)",
+}
+
+// challengePhrases are the lowercase phrases of challenge-response messages
+var challengePhrases = []string{
+	"please confirm that you",
+	"confirm that you are a human",
+	"verify that you are a human",
+	"sender verification",
+	"verify your email address to deliver",
+	"challenge-response",
+	"added to my whitelist",
+	"add you to my whitelist",
+}
+
+// humanReplyPrefixes are the lowercase subject prefixes of replies
+var humanReplyPrefixes = []string{"re:", "aw:", "sv:", "r:", "rif:", "res:", "ответ:", "返信:"}
+
+// signals accumulates the signals found for a kind. Every signal adds its
+// weight to the part of the confidence not covered by the previous ones, and
+// signals found several times are only counted once.
+type signals struct {
+	names      []string
+	confidence float64
+}
+
+func (s *signals) add(name string, weight float64) {
+	for _, n := range s.names {
+		if n == name {
+			return
+		}
+	}
+
+	s.names = append(s.names, name)
+	s.confidence += weight * (1 - s.confidence)
+}
+
+// Classify returns the kind of the message using its headers, such as a null
+// Return-Path, a mailer daemon sender, the report type of multipart/report
+// messages, X-Failed-Recipients or the auto-reply headers of
+// https://tools.ietf.org/html/rfc3834, and the phrases of its subject and body.
+// Every signal found adds to the confidence of its kind and the kind with the
+// highest confidence is returned. Messages with a delivery status, a mailer
+// daemon sender or the status lines and recipient lines of bounces are always
+// bounces, unless they are complaints, as many MTAs send their bounces with
+// auto-reply headers or the In-Reply-To of the original message.
+func Classify(m *Message) Classification {
+	var bounce, complaint, challenge, autoReply, human signals
+	var strong bool
+
+	_, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	switch strings.ToLower(params["report-type"]) {
+	case "delivery-status":
+		bounce.add(SignalDeliveryStatus, 1)
+		strong = true
+	case "feedback-report":
+		complaint.add(SignalFeedbackReport, 1)
+	}
+
+	for _, p := range m.Parts {
+		switch p.MediaType {
+		case "message/delivery-status":
+			bounce.add(SignalDeliveryStatus, 1)
+			strong = true
+		case "message/feedback-report":
+			complaint.add(SignalFeedbackReport, 1)
+		}
+	}
+
+	if isXARF(m.Header) {
+		complaint.add(SignalXARF, 1)
+	}
+
+	if m.Header.Get("X-Failed-Recipients") != "" {
+		bounce.add(SignalFailedRecipients, 0.9)
+	}
+
+	if m.Header.Get("X-MS-Exchange-Message-Is-Ndr") != "" {
+		bounce.add(SignalExchangeNDR, 0.9)
+	}
+
+	if isMailerDaemon(m.Header.Get("From")) {
+		bounce.add(SignalMailerDaemon, 0.8)
+		strong = true
+	}
+
+	if rp := strings.TrimSpace(m.Header.Get("Return-Path")); rp == "<>" {
+		bounce.add(SignalNullReturnPath, 0.3)
+	}
+
+	if v := strings.ToLower(strings.TrimSpace(m.Header.Get("Auto-Submitted"))); v != "" && v != "no" {
+		autoReply.add(SignalAutoSubmitted, 0.9)
+	}
+
+	if m.Header.Get("X-Autoreply") != "" || m.Header.Get("X-Autorespond") != "" {
+		autoReply.add(SignalAutoReplyHeader, 0.9)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(m.Header.Get("Precedence"))) {
+	case "auto_reply", "auto-reply":
+		autoReply.add(SignalPrecedence, 0.8)
+	}
+
+	if m.Header.Get("X-MS-Exchange-Inbox-Rules-Loop") != "" {
+		autoReply.add(SignalExchangeRulesLoop, 0.7)
+	}
+
+	if m.Header.Get("X-Challenge") != "" || m.Header.Get("X-Boxtrapper") != "" {
+		challenge.add(SignalChallengeHeader, 0.9)
+	}
+
+	if m.Header.Get("In-Reply-To") != "" || m.Header.Get("References") != "" {
+		human.add(SignalInReplyTo, 0.3)
+	}
+
+	subject := strings.ToLower(strings.TrimSpace(m.HeaderValue("Subject")))
+	switch {
+	case containsAny(subject, bounceSubjects) || containsPhrase(subject, noticePhrases):
+		bounce.add(SignalSubject, 0.6)
+	case hasAnyPrefix(subject, autoReplySubjects) || containsAny(subject, outOfOfficePhrases):
+		autoReply.add(SignalSubject, 0.7)
+	case containsAny(subject, challengePhrases):
+		challenge.add(SignalSubject, 0.6)
+	case hasAnyPrefix(subject, humanReplyPrefixes):
+		human.add(SignalSubject, 0.4)
+	}
+
+	scan := m.scan()
+	text := scan.lower
+	for _, line := range scan.lowerLines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "status:") && analyzeLine(line[7:]) != NotFound:
+			bounce.add(SignalStatusLine, 0.8)
+			strong = true
+		case isQmailLine(line):
+			bounce.add(SignalQmailRecipient, 0.7)
+			strong = true
+		}
+	}
+
+	switch {
+	case containsPhrase(text, failedPhrases) || containsPhrase(text, delayedPhrases) ||
+		containsPhrase(text, markerPhrases):
+		bounce.add(SignalBody, 0.6)
+	case containsAny(text, challengePhrases):
+		challenge.add(SignalBody, 0.4)
+	}
+
+	result := Classification{Kind: Bounce, Confidence: bounce.confidence, Signals: bounce.names}
+	for _, c := range []struct {
+		kind MessageKind
+		s    signals
+	}{
+		{Complaint, complaint},
+		{ChallengeResponse, challenge},
+		{AutoReply, autoReply},
+		{HumanReply, human},
+	} {
+		if strong && c.kind != Complaint {
+			continue
+		}
+
+		if c.s.confidence > result.Confidence {
+			result = Classification{Kind: c.kind, Confidence: c.s.confidence, Signals: c.s.names}
+		}
+	}
+
+	if result.Kind == AutoReply {
+		result.Kind = m.autoReplyKind()
+	}
+
+	return result
+}
+
+// isMailerDaemon reports whether the address is the one of a mailer daemon
+// or a postmaster
+func isMailerDaemon(from string) bool {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return false
+	}
+
+	local := strings.ToLower(addr.Address)
+	if idx := strings.LastIndex(local, "@"); idx >= 0 {
+		local = local[:idx]
+	}
+
+	return local == "mailer-daemon" || local == "postmaster"
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
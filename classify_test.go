@@ -0,0 +1,99 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestClassify(c *ch.C) {
+	cases := []struct {
+		headers    mail.Header
+		body       string
+		kind       MessageKind
+		confidence float64
+		signals    []string
+	}{
+		{mail.Header{}, "foo", Bounce, 0, nil},
+		{mail.Header{}, msg1, Bounce, 0.6, []string{SignalBody}},
+		{
+			mail.Header{"From": {"Mail Delivery System <MAILER-DAEMON@foo.foo>"}, "Return-Path": {"<>"}},
+			"foo",
+			Bounce,
+			1 - 0.2*0.7,
+			[]string{SignalMailerDaemon, SignalNullReturnPath},
+		},
+		{mail.Header{"X-Failed-Recipients": {"foo@foo.foo"}}, "foo", Bounce, 0.9, []string{SignalFailedRecipients}},
+		{mail.Header{"X-Ms-Exchange-Message-Is-Ndr": {""}}, "foo", Bounce, 0, nil},
+		{mail.Header{"X-Ms-Exchange-Message-Is-Ndr": {"1"}}, "foo", Bounce, 0.9, []string{SignalExchangeNDR}},
+		{mail.Header{"Subject": {"Undeliverable: foo"}}, "foo", Bounce, 0.6, []string{SignalSubject}},
+		{mail.Header{"Subject": {"Unzustellbar: foo"}}, "foo", Bounce, 0.6, []string{SignalSubject}},
+		{
+			mail.Header{"Auto-Submitted": {"auto-replied"}, "Subject": {"Automatic reply: foo"}},
+			"foo",
+			AutoReply,
+			1 - 0.1*0.3,
+			[]string{SignalAutoSubmitted, SignalSubject},
+		},
+		{
+			mail.Header{"From": {"MAILER-DAEMON@foo.foo"}, "Auto-Submitted": {"auto-replied"}},
+			"foo",
+			Bounce,
+			0.8,
+			[]string{SignalMailerDaemon},
+		},
+		{
+			mail.Header{"In-Reply-To": {"<foo@foo.foo>"}},
+			"<foo@foo.foo>: user unknown",
+			Bounce,
+			0.7,
+			[]string{SignalQmailRecipient},
+		},
+		{mail.Header{"In-Reply-To": {"<foo@foo.foo>"}}, "foo", HumanReply, 0.3, []string{SignalInReplyTo}},
+		{mail.Header{"X-Challenge": {"yes"}}, "foo", ChallengeResponse, 0.9, []string{SignalChallengeHeader}},
+		{
+			mail.Header{"Subject": {"Sender verification required"}},
+			"Please confirm that you are a human.",
+			ChallengeResponse,
+			1 - 0.4*0.6,
+			[]string{SignalSubject, SignalBody},
+		},
+		{
+			mail.Header{"Subject": {"Re: foo"}, "In-Reply-To": {"<foo@foo.foo>"}},
+			"Sure, see you tomorrow.",
+			HumanReply,
+			1 - 0.7*0.6,
+			[]string{SignalInReplyTo, SignalSubject},
+		},
+	}
+
+	for _, cs := range cases {
+		result := Classify(NewMessage(cs.headers, []byte(cs.body)))
+		comment := ch.Commentf("headers: %v", cs.headers)
+		c.Assert(result.Kind, Equals, cs.kind, comment)
+		c.Assert(result.Confidence > cs.confidence-1e-9 && result.Confidence < cs.confidence+1e-9, Equals, true, comment)
+		c.Assert(result.Signals, ch.DeepEquals, cs.signals, comment)
+	}
+}
+
+func (s *BounceSuite) TestClassifyReports(c *ch.C) {
+	result := Classify(NewMessage(readMessage(c, dsnMsg)))
+	c.Assert(result.Kind, Equals, Bounce)
+	c.Assert(result.Confidence, Equals, 1.0)
+	c.Assert(result.Signals, ch.DeepEquals, []string{SignalDeliveryStatus, SignalMailerDaemon, SignalSubject, SignalStatusLine})
+	c.Assert(result.IsBounce(), Equals, true)
+
+	result = Classify(NewMessage(readMessage(c, arfMsg)))
+	c.Assert(result.Kind, Equals, Complaint)
+	c.Assert(result.Confidence, Equals, 1.0)
+	c.Assert(result.Signals, ch.DeepEquals, []string{SignalFeedbackReport})
+	c.Assert(result.IsBounce(), Equals, false)
+
+	c.Assert(Analyze(readMessage(c, arfMsg)).Kind, Equals, Complaint)
+}
+
+func (s *BounceSuite) TestClassificationIsBounce(c *ch.C) {
+	c.Assert(Classification{Kind: Bounce}.IsBounce(), Equals, false)
+	c.Assert(Classification{Kind: Bounce, Confidence: 0.5}.IsBounce(), Equals, true)
+	c.Assert(Classification{Kind: AutoReply, Confidence: 1}.IsBounce(), Equals, false)
+}
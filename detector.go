@@ -0,0 +1,340 @@
+package bouncespy
+
+import (
+	"net/textproto"
+	"strings"
+	"unicode"
+)
+
+// Names of the built-in detectors
+const (
+	DeliveryStatusDetector   = "delivery-status"
+	StatusLineDetector       = "status-line"
+	DiagnosticMarkerDetector = "diagnostic-marker"
+	GmailDetector            = "gmail"
+)
+
+// Confidence of the candidates found by the built-in detectors
+const (
+	deliveryStatusConfidence   = 1.0
+	diagnosticCodeConfidence   = 0.9
+	statusLineConfidence       = 0.8
+	diagnosticMarkerConfidence = 0.7
+	gmailConfidence            = 0.5
+)
+
+// Candidate is a possible bounce reason of a message found by a Detector.
+// Candidates with no recipient refer to the message as a whole.
+type Candidate struct {
+	// Detector is the name of the detector that found the candidate, it's set
+	// by the Analyzer.
+	Detector string `json:"detector,omitempty"`
+	// Rule is the name of the rule that found the candidate, if it was found
+	// by a RuleSet.
+	Rule      string       `json:"rule,omitempty"`
+	Recipient string       `json:"recipient,omitempty"`
+	Reason    BounceReason `json:"reason"`
+	// Type is the type of the bounce. If it's nil the type of the reason
+	// is used.
+	Type *BounceType `json:"type,omitempty"`
+	// Category is the category of the bounce. If it's empty the one of the
+	// reason and diagnostic is used.
+	Category   Category `json:"category,omitempty"`
+	Action     Action   `json:"action,omitempty"`
+	Diagnostic string   `json:"diagnostic,omitempty"`
+	// Confidence is a value between 0 and 1 that tells how sure the detector
+	// is about the reason.
+	Confidence float64 `json:"confidence"`
+	// Evidence is the text in which the reason was found.
+	Evidence string `json:"evidence,omitempty"`
+	// Location is where the evidence was found in the message, if the
+	// detector knows it.
+	Location *Location `json:"location,omitempty"`
+}
+
+// isBetterThan reports whether the candidate should be used instead of the
+// other. Among the candidates with no reason, the ones that tell the type or
+// action of the bounce, such as delay notices, are used first.
+func (c Candidate) isBetterThan(o Candidate) bool {
+	if c.Reason == NotFound {
+		return o.Reason == NotFound && c.isDecisive() && !o.isDecisive()
+	} else if o.Reason == NotFound {
+		return true
+	}
+
+	return c.Confidence > o.Confidence
+}
+
+// isDecisive reports whether the candidate determines the result it's used
+// for, that is, whether it has a reason, a type or an action.
+func (c Candidate) isDecisive() bool {
+	return c.Reason != NotFound || c.Type != nil || c.Action != ""
+}
+
+// Detector finds the candidate bounce reasons of a message
+type Detector interface {
+	// Name returns the name of the detector, which is used to disable it in
+	// an Analyzer.
+	Name() string
+	// Detect returns the candidate bounce reasons found in the message. If
+	// several candidates have the same confidence the first one is used.
+	Detect(m *Message) []Candidate
+}
+
+type detectorFunc struct {
+	name string
+	fn   func(*Message) []Candidate
+}
+
+// NewDetector returns a Detector with the given name that uses fn to find
+// the candidates of a message.
+func NewDetector(name string, fn func(m *Message) []Candidate) Detector {
+	return &detectorFunc{name, fn}
+}
+
+func (d *detectorFunc) Name() string                  { return d.name }
+func (d *detectorFunc) Detect(m *Message) []Candidate { return d.fn(m) }
+
+// DefaultDetectors returns the built-in detectors in the order they are used
+// by default.
+func DefaultDetectors() []Detector {
+	return []Detector{
+		NewDetector(DeliveryStatusDetector, detectDeliveryStatus),
+		NewDetector(StatusLineDetector, detectStatusLines),
+		NewDetector(DiagnosticMarkerDetector, detectDiagnosticMarkers),
+		NewDetector(GmailDetector, detectGmailNotices),
+		NewDetector(TextRecipientsDetector, detectTextRecipients),
+	}
+}
+
+// detectDeliveryStatus returns a candidate for every failed or delayed recipient
+// of the delivery status part of the message, even if no reason is found for
+// them, so the recipients are known. The evidence of the candidates is the
+// raw Status or Diagnostic-Code field they were found in.
+func detectDeliveryStatus(m *Message) []Candidate {
+	ds, err := m.DeliveryStatus()
+	if err != nil {
+		return nil
+	}
+
+	fields := m.deliveryStatusFields()
+	// the fields can only be matched with the parsed recipients if the part
+	// has as many recipient blocks as parsed recipients
+	if len(fields) != len(ds.Recipients) {
+		fields = nil
+	}
+
+	var candidates []Candidate
+	for i, r := range ds.Recipients {
+		switch r.Action {
+		case Delivered, Relayed, Expanded:
+			continue
+		}
+
+		c := Candidate{
+			Recipient:  normalizeAddress(r.FinalRecipient),
+			Reason:     NotFound,
+			Action:     r.Action,
+			Diagnostic: r.DiagnosticCode,
+			Confidence: deliveryStatusConfidence,
+		}
+		c.Evidence, c.Location = fieldEvidence(fields, i, "Status", r.Status)
+
+		found := lineCandidates(r.Status, c)
+		if len(found) == 0 {
+			d := c
+			d.Confidence = diagnosticCodeConfidence
+			d.Evidence, d.Location = fieldEvidence(fields, i, "Diagnostic-Code", r.DiagnosticCode)
+			found = lineCandidates(r.DiagnosticCode, d)
+		}
+
+		if len(found) == 0 {
+			found = []Candidate{c}
+		}
+
+		candidates = append(candidates, found...)
+	}
+
+	return candidates
+}
+
+// fieldEvidence returns the raw line of the field of the recipient with the
+// given index and its location, or the field built from its value if the raw
+// line is not known.
+func fieldEvidence(fields []map[string]textLine, i int, key, value string) (string, *Location) {
+	if i < len(fields) {
+		if line, ok := fields[i][key]; ok {
+			return line.text, line.location()
+		}
+	}
+
+	return key + ": " + value, nil
+}
+
+// deliveryStatusFields returns the raw lines of the fields of every recipient
+// of the delivery status part of the message, including their folded lines.
+func (m *Message) deliveryStatusFields() []map[string]textLine {
+	for i, p := range m.Parts {
+		if p.MediaType == "message/delivery-status" {
+			return recipientFields(partLines(p.Body, i))
+		}
+	}
+
+	return nil
+}
+
+// recipientFields returns the raw lines of the fields of the recipient blocks
+// of a delivery status part, that is, all the blocks but the first one.
+func recipientFields(lines []textLine) []map[string]textLine {
+	var blocks []map[string]textLine
+	var block map[string]textLine
+	// key is the field of the last line and raw its lines, which are kept
+	// with their trailing white space to join the folded lines as they are
+	var key, raw string
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line.text) == "":
+			block, key = nil, ""
+		case (line.text[0] == ' ' || line.text[0] == '\t') && key != "":
+			raw += "\n" + line.text
+			field := block[key]
+			field.text = strings.TrimRightFunc(raw, unicode.IsSpace)
+			block[key] = field
+		default:
+			if block == nil {
+				block = make(map[string]textLine)
+				blocks = append(blocks, block)
+			}
+
+			key = ""
+			if idx := strings.Index(line.text, ":"); idx > 0 {
+				key = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(line.text[:idx]))
+				block[key] = line.trim()
+				raw = strings.TrimLeftFunc(line.text, unicode.IsSpace)
+			}
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return blocks[1:]
+}
+
+// lineCandidates returns the given candidate with the reason analyzeLine finds
+// in the line and, if the line has another status code that was compared
+// with it, a candidate with that reason after it, so all the reasons that
+// were considered are reported. No candidates are returned if the line has
+// no reason.
+func lineCandidates(line string, c Candidate) []Candidate {
+	reason, competing := lineReasons(line)
+	if reason == NotFound {
+		return nil
+	}
+
+	c.Reason = reason
+	candidates := []Candidate{c}
+	if competing != NotFound {
+		c.Reason = competing
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// failedRecipients returns the recipients of the delivery status that were
+// not delivered, that is, the failed and delayed ones.
+func failedRecipients(ds *DeliveryStatus) []RecipientStatus {
+	var result []RecipientStatus
+	for _, r := range ds.Recipients {
+		switch r.Action {
+		case Delivered, Relayed, Expanded:
+			continue
+		}
+
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// The line detectors look at the lines of the text of the message from the
+// last one to the first one. Some servers send a bounce email with a more
+// specific error code in the end of the message and a less specific one at
+// the beginning, so the later lines are the ones that should be used first.
+
+func detectStatusLines(m *Message) []Candidate {
+	scan := m.scan()
+	var candidates []Candidate
+	for i := len(scan.lines) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(strings.TrimLeftFunc(scan.lowerLines[i], unicode.IsSpace), "status:") {
+			continue
+		}
+
+		line := scan.lines[i].trim()
+		candidates = append(candidates, lineCandidates(line.text[7:], Candidate{
+			Confidence: statusLineConfidence,
+			Evidence:   line.text,
+			Location:   line.location(),
+		})...)
+	}
+
+	return candidates
+}
+
+func detectDiagnosticMarkers(m *Message) []Candidate {
+	scan := m.scan()
+	var candidates []Candidate
+	for i := len(scan.lines) - 2; i >= 0; i-- {
+		if !endsWithMarker(strings.TrimSpace(scan.lowerLines[i])) {
+			continue
+		}
+
+		next := scan.lines[i+1].trim()
+		candidates = append(candidates, lineCandidates(scan.lines[i+1].text, Candidate{
+			Diagnostic: next.text,
+			Confidence: diagnosticMarkerConfidence,
+			Evidence:   next.text,
+			Location:   next.location(),
+		})...)
+	}
+
+	return candidates
+}
+
+// detectGmailNotices finds the notices of failed and delayed recipients
+// that Gmail and other providers send in any of the languages of the phrase
+// catalogue. As the notices have no status code, failures are reported as
+// UndefinedCode and delays with no reason, as soft bounces.
+func detectGmailNotices(m *Message) []Candidate {
+	scan := m.scan()
+	var candidates []Candidate
+	for i := len(scan.lines) - 1; i >= 0; i-- {
+		lower := scan.lowerLines[i]
+		var reason BounceReason
+		var action Action
+		var typ *BounceType
+		switch {
+		case containsPhrase(lower, delayedPhrases):
+			soft := Soft
+			reason, action, typ = NotFound, Delayed, &soft
+		case containsPhrase(lower, failedPhrases):
+			reason, action = UndefinedCode, Failed
+		default:
+			continue
+		}
+
+		line := scan.lines[i].trim()
+		candidates = append(candidates, Candidate{
+			Reason:     reason,
+			Type:       typ,
+			Action:     action,
+			Confidence: gmailConfidence,
+			Evidence:   line.text,
+			Location:   line.location(),
+		})
+	}
+
+	return candidates
+}
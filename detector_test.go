@@ -0,0 +1,82 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestDefaultDetectors(c *ch.C) {
+	var names []string
+	for _, d := range DefaultDetectors() {
+		names = append(names, d.Name())
+	}
+
+	c.Assert(names, ch.DeepEquals, []string{
+		DeliveryStatusDetector,
+		StatusLineDetector,
+		DiagnosticMarkerDetector,
+		GmailDetector,
+		TextRecipientsDetector,
+	})
+}
+
+func (s *BounceSuite) TestDetectDeliveryStatus(c *ch.C) {
+	candidates := detectDeliveryStatus(NewMessage(readMessage(c, dsnMsg)))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{
+			Recipient:  "foo@foo.foo",
+			Reason:     BadDestinationMailboxAddress,
+			Action:     "failed",
+			Diagnostic: "550 5.1.1 <foo@foo.foo>: Recipient address rejected: User unknown in virtual mailbox table",
+			Confidence: deliveryStatusConfidence,
+			Evidence:   "Status: 5.1.1",
+			Location:   &Location{Part: 1, Offset: 216},
+		},
+		{
+			Recipient:  "baz@foo.foo",
+			Reason:     TransientMailboxFull,
+			Action:     "delayed",
+			Diagnostic: "452 4.2.2 Mailbox full",
+			Confidence: deliveryStatusConfidence,
+			Evidence:   "Status: 4.2.2",
+			Location:   &Location{Part: 1, Offset: 496},
+		},
+	})
+
+	c.Assert(detectDeliveryStatus(NewMessage(mail.Header{}, []byte(msg2))), ch.HasLen, 0)
+}
+
+func (s *BounceSuite) TestDetectStatusLines(c *ch.C) {
+	candidates := detectStatusLines(NewMessage(mail.Header{}, []byte("Status: 4.2.2\nfoo\n  status: 5.0.0 (permanent failure)\n")))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{Reason: AddressDoesntExist, Confidence: statusLineConfidence, Evidence: "status: 5.0.0 (permanent failure)", Location: &Location{0, 20}},
+		{Reason: TransientMailboxFull, Confidence: statusLineConfidence, Evidence: "Status: 4.2.2", Location: &Location{0, 0}},
+	})
+}
+
+func (s *BounceSuite) TestDetectDiagnosticMarkers(c *ch.C) {
+	candidates := detectDiagnosticMarkers(NewMessage(mail.Header{}, []byte(msg1)))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{
+			Reason:     MailboxUnavailable,
+			Diagnostic: "550 Account discontinued, cancelled by user",
+			Confidence: diagnosticMarkerConfidence,
+			Evidence:   "550 Account discontinued, cancelled by user",
+			Location:   &Location{Part: 0, Offset: 294},
+		},
+	})
+}
+
+func (s *BounceSuite) TestDetectGmailNotices(c *ch.C) {
+	candidates := detectGmailNotices(NewMessage(mail.Header{}, []byte(msg6)))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{Reason: UndefinedCode, Action: Failed, Confidence: gmailConfidence, Evidence: "Delivery to the following recipient failed permanently:", Location: &Location{0, 0}},
+	})
+
+	soft := Soft
+	candidates = detectGmailNotices(NewMessage(mail.Header{}, []byte(msg7)))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{Reason: NotFound, Type: &soft, Action: Delayed, Confidence: gmailConfidence, Evidence: "Delivery to the following recipient has been delayed:", Location: &Location{0, 145}},
+	})
+}
@@ -0,0 +1,147 @@
+package bouncespy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// ErrNoDeliveryStatus is returned when the message does not contain a
+// message/delivery-status part
+var ErrNoDeliveryStatus = errors.New("bouncespy: no delivery status part found")
+
+// DeliveryStatus contains the fields of a message/delivery-status part according to
+// https://tools.ietf.org/html/rfc3464#section-2.2
+type DeliveryStatus struct {
+	ReportingMTA       string
+	ArrivalDate        time.Time
+	OriginalEnvelopeID string
+	Recipients         []RecipientStatus
+}
+
+// Action is the action performed by the reporting MTA for a recipient
+// according to https://tools.ietf.org/html/rfc3464#section-2.3.3
+type Action string
+
+const (
+	Failed  Action = "failed"
+	Delayed Action = "delayed"
+	// Delivered, Relayed and Expanded are only reported by delivery status
+	// notifications that were requested for successful deliveries.
+	Delivered Action = "delivered"
+	Relayed   Action = "relayed"
+	Expanded  Action = "expanded"
+)
+
+// RecipientStatus contains the per-recipient fields of a delivery status according to
+// https://tools.ietf.org/html/rfc3464#section-2.3
+// Address type prefixes such as "rfc822;", "dns;" or "smtp;" are removed from the values.
+type RecipientStatus struct {
+	FinalRecipient    string
+	OriginalRecipient string
+	Action            Action
+	Status            string
+	RemoteMTA         string
+	DiagnosticCode    string
+	LastAttemptDate   time.Time
+	WillRetryUntil    time.Time
+}
+
+// BounceReason returns the bounce reason of the recipient. The Status field is
+// used first and the Diagnostic-Code is only used if no reason was found in it.
+func (s RecipientStatus) BounceReason() BounceReason {
+	if reason := analyzeLine(s.Status); reason != NotFound {
+		return reason
+	}
+
+	return analyzeLine(s.DiagnosticCode)
+}
+
+// BounceReason returns the bounce reason of the first recipient that has one
+func (ds *DeliveryStatus) BounceReason() BounceReason {
+	for _, r := range ds.Recipients {
+		if reason := r.BounceReason(); reason != NotFound {
+			return reason
+		}
+	}
+
+	return NotFound
+}
+
+// ParseDeliveryStatus walks the MIME tree of the message with the given headers
+// and body and parses the first message/delivery-status part found in it.
+// ErrNoDeliveryStatus is returned if there is no such part.
+func ParseDeliveryStatus(headers mail.Header, body []byte) (*DeliveryStatus, error) {
+	return NewMessage(headers, body).DeliveryStatus()
+}
+
+func parseDeliveryStatus(content []byte) (*DeliveryStatus, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(content)))
+
+	var ds *DeliveryStatus
+	for {
+		fields, err := r.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if len(fields) > 0 {
+			if ds == nil {
+				ds = &DeliveryStatus{
+					ReportingMTA:       fieldValue(fields, "Reporting-MTA"),
+					ArrivalDate:        fieldDate(fields, "Arrival-Date"),
+					OriginalEnvelopeID: fieldValue(fields, "Original-Envelope-Id"),
+				}
+			} else {
+				ds.Recipients = append(ds.Recipients, RecipientStatus{
+					FinalRecipient:    fieldValue(fields, "Final-Recipient"),
+					OriginalRecipient: fieldValue(fields, "Original-Recipient"),
+					Action:            Action(strings.ToLower(fieldValue(fields, "Action"))),
+					Status:            fieldValue(fields, "Status"),
+					RemoteMTA:         fieldValue(fields, "Remote-MTA"),
+					DiagnosticCode:    fieldValue(fields, "Diagnostic-Code"),
+					LastAttemptDate:   fieldDate(fields, "Last-Attempt-Date"),
+					WillRetryUntil:    fieldDate(fields, "Will-Retry-Until"),
+				})
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if ds == nil {
+		return nil, ErrNoDeliveryStatus
+	}
+
+	return ds, nil
+}
+
+// fieldValue returns the value of the field without its type prefix, that is,
+// "foo@foo.foo" for "rfc822; foo@foo.foo"
+func fieldValue(fields textproto.MIMEHeader, key string) string {
+	value := strings.TrimSpace(fields.Get(key))
+	switch key {
+	case "Final-Recipient", "Original-Recipient", "Reporting-MTA", "Remote-MTA", "Diagnostic-Code":
+		if idx := strings.Index(value, ";"); idx >= 0 {
+			value = strings.TrimSpace(value[idx+1:])
+		}
+	}
+
+	return value
+}
+
+func fieldDate(fields textproto.MIMEHeader, key string) time.Time {
+	date, err := mail.ParseDate(strings.TrimSpace(fields.Get(key)))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return date
+}
@@ -0,0 +1,114 @@
+package bouncespy
+
+import (
+	"io/ioutil"
+	"net/mail"
+	"strings"
+	"time"
+
+	ch "gopkg.in/check.v1"
+)
+
+var dsnMsg = "From: Mail Delivery System <MAILER-DAEMON@mx1.foo.foo>\r\n" +
+	"To: bar@bar.bar\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status;\r\n" +
+	"\tboundary=\"B0UND4RY\"\r\n" +
+	"\r\n" +
+	"This is a MIME-encapsulated message.\r\n" +
+	"\r\n" +
+	"--B0UND4RY\r\n" +
+	"Content-Description: Notification\r\n" +
+	"Content-Type: text/plain; charset=us-ascii\r\n" +
+	"\r\n" +
+	"I'm sorry to have to inform you that your message could not\r\n" +
+	"be delivered to one or more recipients.\r\n" +
+	"\r\n" +
+	"--B0UND4RY\r\n" +
+	"Content-Description: Delivery report\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx1.foo.foo\r\n" +
+	"Original-Envelope-Id: 0123456789\r\n" +
+	"Arrival-Date: Mon, 14 Mar 2016 10:21:33 +0100 (CET)\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; foo@foo.foo\r\n" +
+	"Original-Recipient: rfc822;Foo@foo.foo\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Remote-MTA: dns; mx2.foo.foo\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 <foo@foo.foo>: Recipient address rejected:\r\n" +
+	"    User unknown in virtual mailbox table\r\n" +
+	"Last-Attempt-Date: Mon, 14 Mar 2016 10:21:34 +0100 (CET)\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; baz@foo.foo\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.2.2\r\n" +
+	"Diagnostic-Code: smtp; 452 4.2.2 Mailbox full\r\n" +
+	"Will-Retry-Until: Wed, 16 Mar 2016 10:21:34 +0100 (CET)\r\n" +
+	"\r\n" +
+	"--B0UND4RY\r\n" +
+	"Content-Description: Undelivered Message\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"Subject: Foo\r\n" +
+	"\r\n" +
+	"Foo\r\n" +
+	"\r\n" +
+	"--B0UND4RY--\r\n"
+
+func readMessage(c *ch.C, msg string) (mail.Header, []byte) {
+	m, err := mail.ReadMessage(strings.NewReader(msg))
+	c.Assert(err, ch.IsNil)
+	body, err := ioutil.ReadAll(m.Body)
+	c.Assert(err, ch.IsNil)
+	return m.Header, body
+}
+
+func (s *BounceSuite) TestParseDeliveryStatus(c *ch.C) {
+	ds, err := ParseDeliveryStatus(readMessage(c, dsnMsg))
+	c.Assert(err, ch.IsNil)
+
+	c.Assert(ds.ReportingMTA, Equals, "mx1.foo.foo")
+	c.Assert(ds.OriginalEnvelopeID, Equals, "0123456789")
+	c.Assert(ds.ArrivalDate.Equal(time.Date(2016, 3, 14, 9, 21, 33, 0, time.UTC)), Equals, true)
+	c.Assert(ds.Recipients, ch.HasLen, 2)
+
+	r := ds.Recipients[0]
+	c.Assert(r.FinalRecipient, Equals, "foo@foo.foo")
+	c.Assert(r.OriginalRecipient, Equals, "Foo@foo.foo")
+	c.Assert(r.Action, Equals, Failed)
+	c.Assert(r.Status, Equals, "5.1.1")
+	c.Assert(r.RemoteMTA, Equals, "mx2.foo.foo")
+	c.Assert(r.DiagnosticCode, Equals, "550 5.1.1 <foo@foo.foo>: Recipient address rejected: User unknown in virtual mailbox table")
+	c.Assert(r.LastAttemptDate.Equal(time.Date(2016, 3, 14, 9, 21, 34, 0, time.UTC)), Equals, true)
+	c.Assert(r.WillRetryUntil.IsZero(), Equals, true)
+	c.Assert(r.BounceReason(), Equals, BadDestinationMailboxAddress)
+
+	r = ds.Recipients[1]
+	c.Assert(r.FinalRecipient, Equals, "baz@foo.foo")
+	c.Assert(r.Action, Equals, Delayed)
+	c.Assert(r.WillRetryUntil.Equal(time.Date(2016, 3, 16, 9, 21, 34, 0, time.UTC)), Equals, true)
+	c.Assert(r.BounceReason(), Equals, TransientMailboxFull)
+
+	c.Assert(ds.BounceReason(), Equals, BadDestinationMailboxAddress)
+}
+
+func (s *BounceSuite) TestParseDeliveryStatusNotFound(c *ch.C) {
+	_, err := ParseDeliveryStatus(mail.Header{}, []byte(msg2))
+	c.Assert(err, Equals, ErrNoDeliveryStatus)
+
+	h, body := readMessage(c, "Content-Type: multipart/mixed; boundary=foo\r\n\r\n--foo\r\nContent-Type: text/plain\r\n\r\nfoo\r\n--foo--\r\n")
+	_, err = ParseDeliveryStatus(h, body)
+	c.Assert(err, Equals, ErrNoDeliveryStatus)
+}
+
+func (s *BounceSuite) TestAnalyzeDeliveryStatus(c *ch.C) {
+	result := Analyze(readMessage(c, dsnMsg))
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Type, Equals, Hard)
+
+	result = Analyze(mail.Header{}, []byte(msg2))
+	c.Assert(result.Reason, Equals, AddressDoesntExist)
+}
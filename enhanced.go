@@ -0,0 +1,108 @@
+package bouncespy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidStatusCode is returned when a string is not a valid enhanced status code
+var ErrInvalidStatusCode = errors.New("bouncespy: invalid enhanced status code")
+
+// EnhancedStatusCode is an enhanced mail system status code following the
+// class.subject.detail grammar of https://tools.ietf.org/html/rfc3463#section-2
+type EnhancedStatusCode struct {
+	Class   int
+	Subject int
+	Detail  int
+}
+
+// ParseEnhancedStatusCode parses an enhanced status code such as "5.7.26".
+// The class must be 2, 4 or 5 and the subject and detail must be numbers of
+// up to three digits without leading zeros.
+func ParseEnhancedStatusCode(s string) (EnhancedStatusCode, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) != 3 {
+		return EnhancedStatusCode{}, ErrInvalidStatusCode
+	}
+
+	switch parts[0] {
+	case "2", "4", "5":
+	default:
+		return EnhancedStatusCode{}, ErrInvalidStatusCode
+	}
+
+	var nums [3]int
+	for i, p := range parts {
+		if len(p) == 0 || len(p) > 3 || (len(p) > 1 && p[0] == '0') {
+			return EnhancedStatusCode{}, ErrInvalidStatusCode
+		}
+
+		for j := 0; j < len(p); j++ {
+			if p[j] < '0' || p[j] > '9' {
+				return EnhancedStatusCode{}, ErrInvalidStatusCode
+			}
+			nums[i] = nums[i]*10 + int(p[j]-'0')
+		}
+	}
+
+	return EnhancedStatusCode{nums[0], nums[1], nums[2]}, nil
+}
+
+// String returns the code in its class.subject.detail form
+func (c EnhancedStatusCode) String() string {
+	return fmt.Sprintf("%d.%d.%d", c.Class, c.Subject, c.Detail)
+}
+
+// Reason returns the bounce reason of the exact code, even if it's not
+// present in the StatusMap.
+func (c EnhancedStatusCode) Reason() BounceReason {
+	return BounceReason(c.String())
+}
+
+// KnownReason returns the closest bounce reason of the code present in the
+// StatusMap. If the detail is unknown the X.Subject.0 reason is used and if
+// the subject is unknown the X.0.0 reason is used. NotFound is returned if
+// none of them is known.
+func (c EnhancedStatusCode) KnownReason() BounceReason {
+	candidates := []EnhancedStatusCode{
+		c,
+		{c.Class, c.Subject, 0},
+		{c.Class, 0, 0},
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := StatusMap[candidate.Reason()]; ok {
+			return candidate.Reason()
+		}
+	}
+
+	return NotFound
+}
+
+// knownReason returns the reason itself if it is in the StatusMap or the
+// closest known reason if it is an enhanced status code.
+func knownReason(r BounceReason) BounceReason {
+	if _, ok := StatusMap[r]; ok {
+		return r
+	}
+
+	code, err := ParseEnhancedStatusCode(string(r))
+	if err != nil {
+		return NotFound
+	}
+
+	return code.KnownReason()
+}
+
+// statusInfo returns the status info of the reason using the closest known
+// reason if the reason itself is not in the StatusMap. The type of reasons
+// that are not known is Unknown.
+func statusInfo(r BounceReason) StatusInfo {
+	info, ok := StatusMap[knownReason(r)]
+	if !ok {
+		return StatusInfo{Type: Unknown}
+	}
+
+	return info
+}
@@ -0,0 +1,80 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestParseEnhancedStatusCode(c *ch.C) {
+	cases := []struct {
+		s    string
+		code EnhancedStatusCode
+		err  error
+	}{
+		{"5.1.1", EnhancedStatusCode{5, 1, 1}, nil},
+		{" 4.7.500 ", EnhancedStatusCode{4, 7, 500}, nil},
+		{"5.7.26", EnhancedStatusCode{5, 7, 26}, nil},
+		{"2.0.0", EnhancedStatusCode{2, 0, 0}, nil},
+		{"5.1.10", EnhancedStatusCode{5, 1, 10}, nil},
+		{"1.2.3", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.01.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.1.1000", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.1.1.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.-1.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.+1.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.1.+1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5.a.1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"5..1", EnhancedStatusCode{}, ErrInvalidStatusCode},
+		{"550", EnhancedStatusCode{}, ErrInvalidStatusCode},
+	}
+
+	for _, cs := range cases {
+		code, err := ParseEnhancedStatusCode(cs.s)
+		c.Assert(err, Equals, cs.err, ch.Commentf("code %q", cs.s))
+		c.Assert(code, Equals, cs.code)
+	}
+
+	// signed numbers are not status codes, so only the basic code is used
+	c.Assert(analyzeLine("550 5.+1.1 user unknown"), Equals, MailboxUnavailable)
+}
+
+func (s *BounceSuite) TestEnhancedStatusCodeString(c *ch.C) {
+	c.Assert(EnhancedStatusCode{5, 7, 26}.String(), Equals, "5.7.26")
+	c.Assert(EnhancedStatusCode{4, 2, 2}.Reason(), Equals, TransientMailboxFull)
+}
+
+func (s *BounceSuite) TestEnhancedStatusCodeKnownReason(c *ch.C) {
+	cases := []struct {
+		code   EnhancedStatusCode
+		reason BounceReason
+	}{
+		{EnhancedStatusCode{5, 1, 1}, BadDestinationMailboxAddress},
+		{EnhancedStatusCode{5, 7, 500}, UndefinedSecurityStatus},
+		{EnhancedStatusCode{4, 1, 99}, TransientOtherAddressError},
+		{EnhancedStatusCode{5, 9, 1}, AddressDoesntExist},
+		{EnhancedStatusCode{2, 0, 0}, NotFound},
+	}
+
+	for _, cs := range cases {
+		c.Assert(cs.code.KnownReason(), Equals, cs.reason)
+	}
+}
+
+func (s *BounceSuite) TestUnknownEnhancedReason(c *ch.C) {
+	reason := BounceReason("5.7.500")
+	c.Assert(reason.String(), Equals, "5.7.500 - other or undefined security status")
+	c.Assert(reason.Compare(MailboxUnavailable), Equals, MoreSpecific)
+	c.Assert(parseStatus("5.7.500"), Equals, reason)
+	c.Assert(parseStatus("2.0.0"), Equals, NotFound)
+	c.Assert(analyzeLine("550-5.7.500 message rejected"), Equals, reason)
+
+	result := Analyze(mail.Header{}, []byte("Status: 4.7.500\n"))
+	c.Assert(result.Reason, Equals, BounceReason("4.7.500"))
+	c.Assert(result.Type, Equals, Soft)
+
+	result = Analyze(mail.Header{}, []byte("Status: 5.2.99\n"))
+	c.Assert(result.Reason, Equals, BounceReason("5.2.99"))
+	c.Assert(result.Type, Equals, Soft)
+}
@@ -0,0 +1,62 @@
+package bouncespy
+
+import "bytes"
+
+// Evidence tells where the reason of a result was found, so classifications
+// can be audited.
+type Evidence struct {
+	// Detector is the name of the detector that found the reason.
+	Detector string `json:"detector"`
+	// Rule is the name of the rule that found the reason, if the detector
+	// is a RuleSet.
+	Rule string `json:"rule,omitempty"`
+	// Text is the text the reason was found in, usually a line of the
+	// message.
+	Text string `json:"text"`
+	// Part is the index in the Parts of the message of the part the text was
+	// found in, and Offset is the position of the text in its decoded body.
+	// Both are -1 if the text is not in the body of any part, such as the
+	// evidence found in the headers.
+	Part   int `json:"part"`
+	Offset int `json:"offset"`
+}
+
+// Location is the position of the evidence of a candidate in the message, that
+// is, the index in the Parts of the message of the part it was found in and
+// its offset in the decoded body of the part.
+type Location struct {
+	Part   int `json:"part"`
+	Offset int `json:"offset"`
+}
+
+// newEvidence returns the evidence of the candidate. If the detector did not
+// tell its Location, the text is located in the parts of the message. As
+// detectors prefer the last lines of a message, the last occurrence of the
+// text in the first part that contains it is used.
+func newEvidence(m *Message, c Candidate) *Evidence {
+	e := &Evidence{
+		Detector: c.Detector,
+		Rule:     c.Rule,
+		Text:     c.Evidence,
+		Part:     -1,
+		Offset:   -1,
+	}
+
+	if c.Evidence == "" {
+		return e
+	}
+
+	if c.Location != nil {
+		e.Part, e.Offset = c.Location.Part, c.Location.Offset
+		return e
+	}
+
+	for i, p := range m.Parts {
+		if idx := bytes.LastIndex(p.Body, []byte(c.Evidence)); idx >= 0 {
+			e.Part, e.Offset = i, idx
+			break
+		}
+	}
+
+	return e
+}
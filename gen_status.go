@@ -0,0 +1,245 @@
+//go:build ignore
+// +build ignore
+
+// This program generates status_gen.go from the IANA SMTP Enhanced Status
+// Codes registry in codes/smtp-enhanced-status-codes-1.csv, which can be
+// downloaded from https://www.iana.org/assignments/smtp-enhanced-status-codes/,
+// and the basic status codes in codes/basic-status-codes.csv.
+// Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	enhancedCodesFile = "codes/smtp-enhanced-status-codes-1.csv"
+	basicCodesFile    = "codes/basic-status-codes.csv"
+	outputFile        = "status_gen.go"
+)
+
+// softPermanentCodes are the permanent failures that are considered soft
+// bounces because the mailbox may accept messages again in the future.
+var softPermanentCodes = map[string]bool{
+	"5.2.0": true,
+	"5.2.1": true,
+	"5.2.2": true,
+	"5.3.1": true,
+	"5.4.5": true,
+	"5.5.3": true,
+}
+
+type status struct {
+	code        string
+	typ         string
+	specific    bool
+	description string
+	reference   string
+}
+
+func main() {
+	basic, err := readBasicCodes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enhanced, err := readEnhancedCodes()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	writeFile(&buf, append(basic, enhanced...))
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(outputFile, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readCSV(file string) ([][]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: empty file", file)
+	}
+
+	// the first record is the header
+	return records[1:], nil
+}
+
+func readBasicCodes() ([]status, error) {
+	records, err := readCSV(basicCodesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []status
+	for _, r := range records {
+		if len(r) != 4 {
+			return nil, fmt.Errorf("%s: invalid record %q", basicCodesFile, r)
+		}
+
+		// positive replies such as 251 or 252 are not bounces
+		if strings.HasPrefix(r[0], "2") || strings.HasPrefix(r[0], "3") {
+			return nil, fmt.Errorf("%s: code %s is not a failure", basicCodesFile, r[0])
+		}
+
+		var typ string
+		switch r[1] {
+		case "soft":
+			typ = "Soft"
+		case "hard":
+			typ = "Hard"
+		default:
+			return nil, fmt.Errorf("%s: invalid type %q for code %s", basicCodesFile, r[1], r[0])
+		}
+
+		// codes that are not basic reply codes are specific
+		result = append(result, status{
+			code:        r[0],
+			typ:         typ,
+			specific:    strings.Contains(r[0], "."),
+			description: r[2],
+			reference:   reference(r[3]),
+		})
+	}
+
+	return result, nil
+}
+
+func readEnhancedCodes() ([]status, error) {
+	records, err := readCSV(enhancedCodesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []status
+	for _, class := range []string{"4", "5"} {
+		var codes []status
+		for _, r := range records {
+			if len(r) < 5 || !strings.HasPrefix(r[0], "X.") {
+				return nil, fmt.Errorf("%s: invalid record %q", enhancedCodesFile, r)
+			}
+
+			code := class + r[0][1:]
+			typ := "Hard"
+			if class == "4" || softPermanentCodes[code] {
+				typ = "Soft"
+			}
+
+			codes = append(codes, status{
+				code:        code,
+				typ:         typ,
+				specific:    true,
+				description: description(r[1]),
+				reference:   reference(r[4]),
+			})
+		}
+
+		sort.Slice(codes, func(i, j int) bool {
+			return less(codes[i].code, codes[j].code)
+		})
+		result = append(result, codes...)
+	}
+
+	return result, nil
+}
+
+// less compares two enhanced status codes numerically
+func less(a, b string) bool {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range pa {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na < nb
+		}
+	}
+
+	return false
+}
+
+// description turns the sample text of a code into a lowercase description
+// keeping acronyms such as SPF or DKIM as they are
+func description(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		segments := strings.Split(w, "-")
+		for j, seg := range segments {
+			var upper int
+			for _, r := range seg {
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+
+			if upper <= 1 {
+				segments[j] = strings.ToLower(seg)
+			}
+		}
+		words[i] = strings.Join(segments, "-")
+	}
+
+	return strings.Join(words, " ")
+}
+
+// reference returns the reference without brackets, that is, RFC3463 for [RFC3463]
+func reference(ref string) string {
+	return strings.Trim(strings.TrimSpace(ref), "[]")
+}
+
+func writeFile(buf *bytes.Buffer, statuses []status) {
+	fmt.Fprintln(buf, "// Code generated by gen_status.go. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "package bouncespy")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "// StatusMap is a map indexed by bounce reason that returns its StatusInfo.")
+	fmt.Fprintln(buf, "// Enhanced status codes that are not in the map use the info of the closest")
+	fmt.Fprintln(buf, "// known code, see EnhancedStatusCode.KnownReason.")
+	fmt.Fprintln(buf, "var StatusMap = map[BounceReason]StatusInfo{")
+	for _, s := range statuses {
+		fmt.Fprintf(buf, "\t%q: {%s, %t},\n", s.code, s.typ, s.specific)
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "var reasonDescriptions = map[BounceReason]string{")
+	for _, s := range statuses {
+		fmt.Fprintf(buf, "\t%q: %q,\n", s.code, s.description)
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "var reasonReferences = map[BounceReason]string{")
+	for _, s := range statuses {
+		if s.reference != "" {
+			fmt.Fprintf(buf, "\t%q: %q,\n", s.code, s.reference)
+		}
+	}
+	fmt.Fprintln(buf, "}")
+}
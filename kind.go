@@ -0,0 +1,129 @@
+package bouncespy
+
+import "strings"
+
+// MessageKind defines the kind of message analyzed, that is, a bounce or any
+// other message that may arrive to the address bounces are sent to, such as
+// an automatic reply sent by a mailbox that is still alive
+type MessageKind int
+
+const (
+	Bounce MessageKind = iota
+	// AutoReply is an automatic reply that is neither an out of office
+	// message nor a notice of someone that left the company.
+	AutoReply
+	OutOfOffice
+	LeftCompany
+	// Complaint is a feedback report of a recipient that marked a message
+	// as spam.
+	Complaint
+	// ChallengeResponse is a request to prove that the sender is a human
+	// before the message is delivered.
+	ChallengeResponse
+	HumanReply
+)
+
+var messageKindNames = map[MessageKind]string{
+	Bounce:            "bounce",
+	AutoReply:         "auto-reply",
+	OutOfOffice:       "out-of-office",
+	LeftCompany:       "left-company",
+	Complaint:         "complaint",
+	ChallengeResponse: "challenge-response",
+	HumanReply:        "human-reply",
+}
+
+func (k MessageKind) String() string {
+	return messageKindNames[k]
+}
+
+// autoReplySubjects are the lowercase subject prefixes of automatic replies
+var autoReplySubjects = []string{
+	"auto:",
+	"autoreply",
+	"auto-reply",
+	"auto reply",
+	"automatic reply",
+	"automatische antwort",
+	"réponse automatique",
+	"respuesta automática",
+	"resposta automática",
+	"risposta automatica",
+	"自動応答",
+	"自動返信",
+	"автоматический ответ",
+}
+
+// outOfOfficePhrases are lowercase phrases of out of office messages
+var outOfOfficePhrases = []string{
+	"out of office",
+	"out of the office",
+	"on vacation",
+	"on holiday",
+	"on leave",
+	"abwesenheitsnotiz",
+	"nicht im büro",
+	"abwesend",
+	"absent du bureau",
+	"en congé",
+	"fuera de la oficina",
+	"de vacaciones",
+	"fora do escritório",
+	"de férias",
+	"fuori ufficio",
+	"in ferie",
+	"不在",
+	"休暇",
+	"вне офиса",
+	"в отпуске",
+}
+
+// leftCompanyPhrases are lowercase phrases of notices of someone that no
+// longer works in the company
+var leftCompanyPhrases = []string{
+	"no longer with",
+	"no longer employed",
+	"no longer works",
+	"has left the company",
+	"left the company",
+	"nicht mehr im unternehmen",
+	"nicht mehr für",
+	"ne fait plus partie",
+	"a quitté la société",
+	"ya no trabaja",
+	"ya no forma parte",
+	"não faz mais parte",
+	"não trabalha mais",
+	"non lavora più",
+	"non fa più parte",
+	"退職",
+	"больше не работает",
+}
+
+// Kind returns the kind of the message, see Classify
+func (m *Message) Kind() MessageKind {
+	return Classify(m).Kind
+}
+
+// autoReplyKind returns the kind of automatic reply of the message
+func (m *Message) autoReplyKind() MessageKind {
+	subject, text := strings.ToLower(m.HeaderValue("Subject")), m.scan().lower
+	switch {
+	case containsAny(subject, leftCompanyPhrases) || containsAny(text, leftCompanyPhrases):
+		return LeftCompany
+	case containsAny(subject, outOfOfficePhrases) || containsAny(text, outOfOfficePhrases):
+		return OutOfOffice
+	default:
+		return AutoReply
+	}
+}
+
+func containsAny(s string, phrases []string) bool {
+	for _, p := range phrases {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"strings"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestMessageKind(c *ch.C) {
+	cases := []struct {
+		headers mail.Header
+		body    string
+		kind    MessageKind
+	}{
+		{mail.Header{}, msg1, Bounce},
+		{mail.Header{"Auto-Submitted": {"auto-replied"}}, "Thanks for your message.", AutoReply},
+		{mail.Header{"Auto-Submitted": {"no"}}, "Thanks for your message.", Bounce},
+		{mail.Header{"X-Autoreply": {"yes"}}, "I'm on vacation until Monday.", OutOfOffice},
+		{mail.Header{"X-Autorespond": {"yes"}}, "Foo is no longer with ACME.", LeftCompany},
+		{mail.Header{"Precedence": {"auto_reply"}}, "foo", AutoReply},
+		{mail.Header{"X-Ms-Exchange-Inbox-Rules-Loop": {"foo@foo.foo"}}, "foo", AutoReply},
+		{mail.Header{"Subject": {"Out of Office: foo"}}, "foo", OutOfOffice},
+		{mail.Header{"Subject": {"Automatic reply: foo"}}, "foo", AutoReply},
+		{mail.Header{"Subject": {"Abwesenheitsnotiz: foo"}}, "foo", OutOfOffice},
+		{mail.Header{"Subject": {"Réponse automatique : foo"}}, "Je suis absent du bureau.", OutOfOffice},
+		{mail.Header{"Subject": {"Respuesta automática: foo"}}, "Foo ya no trabaja en ACME.", LeftCompany},
+		{mail.Header{"Subject": {"=?UTF-8?B?6Ieq5YuV5b+c562U?="}}, "foo", AutoReply},
+		{mail.Header{"Subject": {"Автоматический ответ: foo"}}, "Я в отпуске.", OutOfOffice},
+		{
+			mail.Header{"Auto-Submitted": {"auto-replied"}, "From": {"MAILER-DAEMON@foo.foo"}},
+			msg1,
+			Bounce,
+		},
+		{mail.Header{"Subject": {"Re: foo"}}, "I'm on vacation", HumanReply},
+	}
+
+	for _, cs := range cases {
+		m := NewMessage(cs.headers, []byte(cs.body))
+		c.Assert(m.Kind(), Equals, cs.kind, ch.Commentf("headers: %v", cs.headers))
+	}
+
+	header, body := readMessage(c, dsnMsg)
+	header["Auto-Submitted"] = []string{"auto-replied"}
+	c.Assert(NewMessage(header, body).Kind(), Equals, Bounce)
+}
+
+func (s *BounceSuite) TestMessageKindString(c *ch.C) {
+	c.Assert(Bounce.String(), Equals, "bounce")
+	c.Assert(AutoReply.String(), Equals, "auto-reply")
+	c.Assert(OutOfOffice.String(), Equals, "out-of-office")
+	c.Assert(LeftCompany.String(), Equals, "left-company")
+	c.Assert(Complaint.String(), Equals, "complaint")
+	c.Assert(ChallengeResponse.String(), Equals, "challenge-response")
+	c.Assert(HumanReply.String(), Equals, "human-reply")
+}
+
+func (s *BounceSuite) TestAnalyzeAutoReply(c *ch.C) {
+	result := Analyze(
+		mail.Header{"Subject": {"Out of office"}, "Auto-Submitted": {"auto-replied"}},
+		[]byte("I'm out of the office and my mailbox is full: 5.2.2"),
+	)
+	c.Assert(result, ch.DeepEquals, Result{Type: Unknown, Reason: NotFound, Category: CategoryUnknown, Kind: OutOfOffice})
+
+	// bounces sent with auto-reply headers or the In-Reply-To of the original
+	// message are still bounces
+	header, body := readMessage(c, dsnMsg)
+	header["Auto-Submitted"] = []string{"auto-replied"}
+	result = Analyze(header, body)
+	c.Assert(result.Kind, Equals, Bounce)
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+
+	result = Analyze(
+		mail.Header{"From": {"MAILER-DAEMON@foo.foo"}, "Auto-Submitted": {"auto-replied"}},
+		[]byte("Your message could not be delivered to foo@foo.foo: 5.1.1 user unknown"),
+	)
+	c.Assert(result.Kind, Equals, Bounce)
+
+	result = Analyze(mail.Header{"In-Reply-To": {"<foo@foo.foo>"}}, []byte("Final-Recipient: rfc822;foo@foo.foo\nStatus: 5.1.1"))
+	c.Assert(result.Kind, Equals, Bounce)
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+
+	result = Analyze(
+		mail.Header{"Auto-Submitted": {"auto-replied"}},
+		[]byte("<foo@foo.foo>: host mx.foo.foo said: 550 5.1.1 user unknown"),
+	)
+	c.Assert(result.Kind, Equals, Bounce)
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+
+	result = Analyze(mail.Header{}, []byte(msg1))
+	c.Assert(result.Kind, Equals, Bounce)
+	c.Assert(strings.HasPrefix(string(result.Reason), "5"), Equals, true)
+}
@@ -0,0 +1,300 @@
+package bouncespy
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The enums of the package are encoded as text, JSON strings and SQL text
+// values with their stable names, such as "hard", "5.1.1" or "bad_mailbox".
+// Decoding is strict and values that are not known are rejected with an
+// error. The empty string is the zero value of the string enums, such as
+// NotFound, and SQL NULL values are scanned as the empty string. NULL bounce
+// types are scanned as Unknown and the rest of the enums cannot be NULL.
+
+func marshalJSONText(m encoding.TextMarshaler) ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(text))
+}
+
+func unmarshalJSONText(data []byte, u encoding.TextUnmarshaler) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	return u.UnmarshalText([]byte(s))
+}
+
+func valueText(m encoding.TextMarshaler) (driver.Value, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return string(text), nil
+}
+
+func scanText(src interface{}, u encoding.TextUnmarshaler) error {
+	switch v := src.(type) {
+	case string:
+		return u.UnmarshalText([]byte(v))
+	case []byte:
+		return u.UnmarshalText(v)
+	case nil:
+		return u.UnmarshalText(nil)
+	default:
+		return fmt.Errorf("bouncespy: cannot scan %T", src)
+	}
+}
+
+// parseReason returns the reason with the given code, which must be in the
+// StatusMap or be an enhanced status code with a known less specific code.
+// Codes must be in their canonical form, without white space or signs, so
+// they are stored as they are found in the StatusMap.
+func parseReason(s string) (BounceReason, error) {
+	r := BounceReason(s)
+	if _, ok := StatusMap[r]; ok || r == NotFound {
+		return r, nil
+	}
+
+	code, err := ParseEnhancedStatusCode(s)
+	if err != nil || code.String() != s || code.KnownReason() == NotFound {
+		return NotFound, fmt.Errorf("bouncespy: invalid bounce reason %q", s)
+	}
+
+	return r, nil
+}
+
+// MarshalText returns the status code of the reason
+func (r BounceReason) MarshalText() ([]byte, error) { return []byte(r), nil }
+
+// UnmarshalText parses a status code. Unknown codes are rejected.
+func (r *BounceReason) UnmarshalText(text []byte) error {
+	reason, err := parseReason(string(text))
+	if err != nil {
+		return err
+	}
+
+	*r = reason
+	return nil
+}
+
+func (r BounceReason) MarshalJSON() ([]byte, error)     { return marshalJSONText(r) }
+func (r *BounceReason) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, r) }
+func (r BounceReason) Value() (driver.Value, error)     { return valueText(r) }
+func (r *BounceReason) Scan(src interface{}) error      { return scanText(src, r) }
+
+// MarshalText returns the name of the type, such as "hard"
+func (t BounceType) MarshalText() ([]byte, error) {
+	name, ok := bounceTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("bouncespy: invalid bounce type %d", int(t))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText parses the name of a type
+func (t *BounceType) UnmarshalText(text []byte) error {
+	typ, err := parseBounceType(string(text))
+	if err != nil {
+		return err
+	}
+
+	*t = typ
+	return nil
+}
+
+func (t BounceType) MarshalJSON() ([]byte, error)     { return marshalJSONText(t) }
+func (t *BounceType) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, t) }
+func (t BounceType) Value() (driver.Value, error)     { return valueText(t) }
+
+// Scan scans a type name. NULL is scanned as Unknown, as Soft is the zero
+// value of the type and a missing type is not a soft bounce.
+func (t *BounceType) Scan(src interface{}) error {
+	if src == nil {
+		*t = Unknown
+		return nil
+	}
+
+	return scanText(src, t)
+}
+
+// MarshalText returns the name of the category
+func (c Category) MarshalText() ([]byte, error) {
+	if c == "" {
+		return nil, nil
+	}
+
+	category, err := parseCategory(string(c))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(category), nil
+}
+
+// UnmarshalText parses the name of a category
+func (c *Category) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = ""
+		return nil
+	}
+
+	category, err := parseCategory(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = category
+	return nil
+}
+
+func (c Category) MarshalJSON() ([]byte, error)     { return marshalJSONText(c) }
+func (c *Category) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, c) }
+func (c Category) Value() (driver.Value, error)     { return valueText(c) }
+func (c *Category) Scan(src interface{}) error      { return scanText(src, c) }
+
+var actions = []Action{Failed, Delayed, Delivered, Relayed, Expanded}
+
+func parseAction(s string) (Action, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	for _, a := range actions {
+		if strings.EqualFold(strings.TrimSpace(s), string(a)) {
+			return a, nil
+		}
+	}
+
+	return "", fmt.Errorf("bouncespy: invalid action %q", s)
+}
+
+// MarshalText returns the name of the action
+func (a Action) MarshalText() ([]byte, error) {
+	action, err := parseAction(string(a))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(action), nil
+}
+
+// UnmarshalText parses the name of an action
+func (a *Action) UnmarshalText(text []byte) error {
+	action, err := parseAction(string(text))
+	if err != nil {
+		return err
+	}
+
+	*a = action
+	return nil
+}
+
+func (a Action) MarshalJSON() ([]byte, error)     { return marshalJSONText(a) }
+func (a *Action) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, a) }
+func (a Action) Value() (driver.Value, error)     { return valueText(a) }
+func (a *Action) Scan(src interface{}) error      { return scanText(src, a) }
+
+// MarshalText returns the name of the kind, such as "out-of-office"
+func (k MessageKind) MarshalText() ([]byte, error) {
+	name, ok := messageKindNames[k]
+	if !ok {
+		return nil, fmt.Errorf("bouncespy: invalid message kind %d", int(k))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText parses the name of a kind
+func (k *MessageKind) UnmarshalText(text []byte) error {
+	for kind, name := range messageKindNames {
+		if strings.EqualFold(strings.TrimSpace(string(text)), name) {
+			*k = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("bouncespy: invalid message kind %q", text)
+}
+
+func (k MessageKind) MarshalJSON() ([]byte, error)     { return marshalJSONText(k) }
+func (k *MessageKind) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, k) }
+func (k MessageKind) Value() (driver.Value, error)     { return valueText(k) }
+func (k *MessageKind) Scan(src interface{}) error      { return scanText(src, k) }
+
+// MarshalText returns the name of the status, such as "valid"
+func (s SignatureStatus) MarshalText() ([]byte, error) {
+	name, ok := signatureStatusNames[s]
+	if !ok {
+		return nil, fmt.Errorf("bouncespy: invalid signature status %d", int(s))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText parses the name of a status
+func (s *SignatureStatus) UnmarshalText(text []byte) error {
+	for status, name := range signatureStatusNames {
+		if strings.EqualFold(strings.TrimSpace(string(text)), name) {
+			*s = status
+			return nil
+		}
+	}
+
+	return fmt.Errorf("bouncespy: invalid signature status %q", text)
+}
+
+func (s SignatureStatus) MarshalJSON() ([]byte, error)     { return marshalJSONText(s) }
+func (s *SignatureStatus) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, s) }
+func (s SignatureStatus) Value() (driver.Value, error)     { return valueText(s) }
+func (s *SignatureStatus) Scan(src interface{}) error      { return scanText(src, s) }
+
+// parseFeedbackType returns the lowercase feedback type. Feedback types are
+// not restricted to the registered ones, as some feedback loops and X-ARF
+// reports use their own, but they must be tokens.
+func parseFeedbackType(s string) (FeedbackType, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return "", fmt.Errorf("bouncespy: invalid feedback type %q", s)
+		}
+	}
+
+	return FeedbackType(s), nil
+}
+
+// MarshalText returns the name of the feedback type, such as "abuse"
+func (f FeedbackType) MarshalText() ([]byte, error) {
+	typ, err := parseFeedbackType(string(f))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(typ), nil
+}
+
+// UnmarshalText parses the name of a feedback type
+func (f *FeedbackType) UnmarshalText(text []byte) error {
+	typ, err := parseFeedbackType(string(text))
+	if err != nil {
+		return err
+	}
+
+	*f = typ
+	return nil
+}
+
+func (f FeedbackType) MarshalJSON() ([]byte, error)     { return marshalJSONText(f) }
+func (f *FeedbackType) UnmarshalJSON(data []byte) error { return unmarshalJSONText(data, f) }
+func (f FeedbackType) Value() (driver.Value, error)     { return valueText(f) }
+func (f *FeedbackType) Scan(src interface{}) error      { return scanText(src, f) }
@@ -0,0 +1,197 @@
+package bouncespy
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+type enum interface {
+	encoding.TextMarshaler
+	json.Marshaler
+	driver.Valuer
+}
+
+type enumPtr interface {
+	encoding.TextUnmarshaler
+	json.Unmarshaler
+	sql.Scanner
+}
+
+func (s *BounceSuite) TestEnumsMarshal(c *ch.C) {
+	reason, typ, category, action := BounceReason(""), Soft, Category(""), Action("")
+	kind, status, feedback := Bounce, SignatureUnchecked, FeedbackType("")
+
+	cases := []struct {
+		value   enum
+		ptr     enumPtr
+		text    string
+		invalid string
+	}{
+		{BadDestinationMailboxAddress, &reason, "5.1.1", "5.1.99x"},
+		{BounceReason("5.7.500"), &reason, "5.7.500", "600"},
+		{NotFound, &reason, "", "foo"},
+		{MailboxUnavailable, &reason, "550", " 550"},
+		{BadDestinationMailboxAddress, &reason, "5.1.1", "5.1.1 "},
+		{BadDestinationMailboxAddress, &reason, "5.1.1", "5.+1.1"},
+		{Hard, &typ, "hard", "medium"},
+		{Unknown, &typ, "unknown", ""},
+		{CategoryMailboxFull, &category, "mailbox_full", "full"},
+		{Delayed, &action, "delayed", "bounced"},
+		{OutOfOffice, &kind, "out-of-office", "vacation"},
+		{SignatureForged, &status, "forged", "fake"},
+		{FeedbackAbuse, &feedback, "abuse", "not spam"},
+		{FeedbackType("spam"), &feedback, "spam", "spam!"},
+	}
+
+	for _, cs := range cases {
+		comment := ch.Commentf("value: %v", cs.value)
+
+		text, err := cs.value.MarshalText()
+		c.Assert(err, ch.IsNil, comment)
+		c.Assert(string(text), Equals, cs.text, comment)
+
+		data, err := cs.value.MarshalJSON()
+		c.Assert(err, ch.IsNil, comment)
+		c.Assert(string(data), Equals, `"`+cs.text+`"`, comment)
+
+		value, err := cs.value.Value()
+		c.Assert(err, ch.IsNil, comment)
+		c.Assert(value, Equals, cs.text, comment)
+
+		c.Assert(cs.ptr.UnmarshalText([]byte(cs.text)), ch.IsNil, comment)
+		c.Assert(cs.ptr, ch.DeepEquals, ptrTo(cs.value), comment)
+
+		c.Assert(cs.ptr.UnmarshalJSON(data), ch.IsNil, comment)
+		c.Assert(cs.ptr, ch.DeepEquals, ptrTo(cs.value), comment)
+
+		c.Assert(cs.ptr.Scan([]byte(cs.text)), ch.IsNil, comment)
+		c.Assert(cs.ptr, ch.DeepEquals, ptrTo(cs.value), comment)
+
+		c.Assert(cs.ptr.UnmarshalText([]byte(cs.invalid)), ch.NotNil, comment)
+		c.Assert(cs.ptr.UnmarshalJSON([]byte(`"`+cs.invalid+`"`)), ch.NotNil, comment)
+		c.Assert(cs.ptr.Scan(cs.invalid), ch.NotNil, comment)
+		c.Assert(cs.ptr.Scan(42), ch.NotNil, comment)
+	}
+}
+
+// ptrTo returns a pointer to a copy of the given enum value
+func ptrTo(v enum) interface{} {
+	switch v := v.(type) {
+	case BounceReason:
+		return &v
+	case BounceType:
+		return &v
+	case Category:
+		return &v
+	case Action:
+		return &v
+	case MessageKind:
+		return &v
+	case SignatureStatus:
+		return &v
+	case FeedbackType:
+		return &v
+	}
+
+	panic("unknown enum")
+}
+
+func (s *BounceSuite) TestEnumsMarshalInvalidValues(c *ch.C) {
+	_, err := BounceType(42).MarshalText()
+	c.Assert(err, ch.NotNil)
+	_, err = Category("full").MarshalJSON()
+	c.Assert(err, ch.NotNil)
+	_, err = Action("bounced").Value()
+	c.Assert(err, ch.NotNil)
+	_, err = MessageKind(42).MarshalText()
+	c.Assert(err, ch.NotNil)
+	_, err = SignatureStatus(42).MarshalText()
+	c.Assert(err, ch.NotNil)
+	_, err = FeedbackType("not spam").MarshalText()
+	c.Assert(err, ch.NotNil)
+
+	var reason BounceReason = MailboxFull
+	c.Assert(reason.Scan(nil), ch.IsNil)
+	c.Assert(reason, Equals, NotFound)
+
+	// a NULL type is not known, rather than the zero value, which is Soft
+	typ, kind, status, feedback := Hard, OutOfOffice, SignatureForged, FeedbackAbuse
+	c.Assert(typ.Scan(nil), ch.IsNil)
+	c.Assert(typ, Equals, Unknown)
+	c.Assert(kind.Scan(nil), ch.NotNil)
+	c.Assert(kind, Equals, OutOfOffice)
+	c.Assert(status.Scan(nil), ch.NotNil)
+	c.Assert(status, Equals, SignatureForged)
+	c.Assert(feedback.Scan(nil), ch.IsNil)
+	c.Assert(feedback, Equals, FeedbackType(""))
+
+	var category Category = CategoryMailboxFull
+	var action Action = Failed
+	c.Assert(category.Scan(nil), ch.IsNil)
+	c.Assert(category, Equals, Category(""))
+	c.Assert(action.Scan(nil), ch.IsNil)
+	c.Assert(action, Equals, Action(""))
+}
+
+func (s *BounceSuite) TestResultJSON(c *ch.C) {
+	result := Analyze(readMessage(c, dsnMsg))
+	data, err := json.Marshal(result)
+	c.Assert(err, ch.IsNil)
+
+	var fields map[string]interface{}
+	c.Assert(json.Unmarshal(data, &fields), ch.IsNil)
+	c.Assert(fields["type"], Equals, "hard")
+	c.Assert(fields["reason"], Equals, "5.1.1")
+	c.Assert(fields["action"], Equals, "failed")
+	c.Assert(fields["category"], Equals, "bad_mailbox")
+	c.Assert(fields["recipient"], Equals, "foo@foo.foo")
+	c.Assert(fields["kind"], Equals, "bounce")
+	c.Assert(fields["signature"], Equals, "unchecked")
+	c.Assert(fields["confidence"], Equals, 1.0)
+	c.Assert(fields["evidence"], ch.DeepEquals, map[string]interface{}{
+		"detector": "delivery-status",
+		"text":     "Status: 5.1.1",
+		"part":     1.0,
+		"offset":   216.0,
+	})
+
+	var decoded Result
+	c.Assert(json.Unmarshal(data, &decoded), ch.IsNil)
+	c.Assert(decoded.Type, Equals, result.Type)
+	c.Assert(decoded.Reason, Equals, result.Reason)
+	c.Assert(decoded.Category, Equals, result.Category)
+	c.Assert(decoded.Candidates, ch.DeepEquals, result.Candidates)
+
+	result = Analyze(mail.Header{}, nil)
+	data, err = json.Marshal(result)
+	c.Assert(err, ch.IsNil)
+	c.Assert(string(data), Equals, `{"type":"unknown","reason":"","category":"unknown","confidence":0,"spam_score":0,"spam":{"present":false,"score":0},"kind":"bounce","signature":"unchecked"}`)
+
+	err = json.Unmarshal([]byte(`{"type":"hard","reason":"5.1.99x"}`), &decoded)
+	c.Assert(err, ch.ErrorMatches, `.*invalid bounce reason "5.1.99x"`)
+}
+
+func (s *BounceSuite) TestLoadRulesInvalidEnums(c *ch.C) {
+	dir := c.MkDir()
+	path := writeRules(c, dir, "invalid.json", `[{"name": "foo", "match": {"body": "foo"}, "reason": "5.1.99x"}]`)
+	_, err := LoadRules(path)
+	c.Assert(err, ch.FitsTypeOf, &RuleError{})
+	c.Assert(err.(*RuleError).File, Equals, path)
+	c.Assert(err.(*RuleError).Index, Equals, 0)
+	c.Assert(err.(*RuleError).Name, Equals, "foo")
+	c.Assert(err.(*RuleError).Field, Equals, "reason")
+
+	path = writeRules(c, dir, "invalid.yml", "- name: foo\n  match:\n    body: foo\n  reason: 5.1.1\n  category: full\n")
+	_, err = LoadRules(path)
+	c.Assert(err, ch.FitsTypeOf, &RuleError{})
+	c.Assert(err.(*RuleError).File, Equals, path)
+	c.Assert(err.(*RuleError).Index, Equals, 0)
+	c.Assert(err.(*RuleError).Name, Equals, "foo")
+	c.Assert(err.(*RuleError).Field, Equals, "category")
+	c.Assert(err, ch.ErrorMatches, `.*invalid.yml.*invalid category.*`)
+}
@@ -0,0 +1,285 @@
+package bouncespy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Part is a leaf part of a message. Its body is already decoded from the
+// Content-Transfer-Encoding of the part and, for text parts, converted to UTF-8
+// from the charset of the part.
+type Part struct {
+	Header    textproto.MIMEHeader
+	MediaType string
+	Params    map[string]string
+	Body      []byte
+}
+
+// Message is an email message with all the leaf parts of its MIME tree.
+type Message struct {
+	Header mail.Header
+	Parts  []Part
+
+	scanOnce sync.Once
+	scanned  *textScan
+
+	originalOnce sync.Once
+	original     *OriginalMessage
+}
+
+// ReadMessage reads a complete raw email message from the given reader and
+// returns it parsed.
+func ReadMessage(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMessage(msg.Header, body), nil
+}
+
+// NewMessage returns a Message given the headers and body of an email message.
+// All multipart levels are walked to find the leaf parts of the message. Parsing
+// is lenient: a multipart body that cannot be parsed is kept as a text part.
+func NewMessage(headers mail.Header, body []byte) *Message {
+	m := &Message{Header: headers}
+	m.addParts(textproto.MIMEHeader(headers), body)
+	return m
+}
+
+// HeaderValue returns the first value of the given header with its RFC 2047
+// encoded words decoded.
+func (m *Message) HeaderValue(key string) string {
+	return decodeHeader(m.Header.Get(key))
+}
+
+func (m *Message) addParts(header textproto.MIMEHeader, body []byte) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		parts, err := readMultipart(body, params["boundary"])
+		if err == nil {
+			for _, p := range parts {
+				m.addParts(p.Header, p.Body)
+			}
+			return
+		}
+
+		mediaType = "text/plain"
+	}
+
+	body = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	if strings.HasPrefix(mediaType, "text/") {
+		body = decodeCharset(params["charset"], body)
+	}
+
+	m.Parts = append(m.Parts, Part{
+		Header:    header,
+		MediaType: mediaType,
+		Params:    params,
+		Body:      body,
+	})
+}
+
+func readMultipart(body []byte, boundary string) ([]Part, error) {
+	var parts []Part
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, Part{Header: p.Header, Body: content})
+	}
+}
+
+// decodeTransferEncoding decodes the body with the given Content-Transfer-Encoding.
+// If the body cannot be decoded it is returned as is.
+func decodeTransferEncoding(encoding string, body []byte) []byte {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(bytes.NewReader(body))
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(body))
+	default:
+		return body
+	}
+
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return body
+	}
+
+	return decoded
+}
+
+// DeliveryStatus parses the first message/delivery-status part of the message.
+// ErrNoDeliveryStatus is returned if there is no such part.
+func (m *Message) DeliveryStatus() (*DeliveryStatus, error) {
+	for _, p := range m.Parts {
+		if p.MediaType == "message/delivery-status" {
+			return parseDeliveryStatus(p.Body)
+		}
+	}
+
+	return nil, ErrNoDeliveryStatus
+}
+
+// textParts returns the indexes of the human-readable and delivery status parts
+// of the message, which are the only ones that can be used to find the bounce
+// reason. If there is no text/plain part, any other text part is used instead.
+func (m *Message) textParts() []int {
+	var plain, other, status []int
+	for i, p := range m.Parts {
+		switch {
+		case p.MediaType == "message/delivery-status":
+			status = append(status, i)
+		case p.MediaType == "text/plain":
+			plain = append(plain, i)
+		case strings.HasPrefix(p.MediaType, "text/") && p.MediaType != "text/rfc822-headers":
+			other = append(other, i)
+		}
+	}
+
+	if len(plain) == 0 {
+		plain = other
+	}
+
+	return append(plain, status...)
+}
+
+// textScan is the text of the text parts of the message, see textParts,
+// which is read once and shared by the detectors and classifiers so the body
+// of large messages is not copied and split again by each one of them.
+type textScan struct {
+	// text is the content of the text parts joined by a newline.
+	text string
+	// lower is the lowercase text.
+	lower string
+	// lines are the lines of the text and lowerLines their lowercase
+	// versions, with the same indexes.
+	lines      []textLine
+	lowerLines []string
+}
+
+// scan returns the text of the message, which is read the first time it's
+// needed. The returned scan must not be modified.
+func (m *Message) scan() *textScan {
+	m.scanOnce.Do(func() {
+		parts := m.textParts()
+		var size int
+		for _, i := range parts {
+			size += len(m.Parts[i].Body) + 1
+		}
+
+		var b strings.Builder
+		b.Grow(size)
+		starts := make([]int, len(parts))
+		for k, i := range parts {
+			if k > 0 {
+				b.WriteByte('\n')
+			}
+			starts[k] = b.Len()
+			b.Write(m.Parts[i].Body)
+		}
+
+		scan := &textScan{text: b.String()}
+		for k, i := range parts {
+			body := scan.text[starts[k] : starts[k]+len(m.Parts[i].Body)]
+			scan.lines = append(scan.lines, stringLines(body, i)...)
+		}
+
+		// lowercasing does not add or remove newlines, so the lines of the
+		// lowercase text match the lines of the text
+		scan.lower = strings.ToLower(scan.text)
+		if len(scan.lines) > 0 {
+			scan.lowerLines = strings.Split(scan.lower, "\n")
+		}
+		m.scanned = scan
+	})
+
+	return m.scanned
+}
+
+// text returns the content of the text parts of the message, see textParts
+func (m *Message) text() string {
+	return m.scan().text
+}
+
+// textLine is a line of a text part of the message along with the index of
+// the part and its offset in the body of the part
+type textLine struct {
+	text   string
+	part   int
+	offset int
+}
+
+// partLines returns the lines of the body of the part with the given index
+func partLines(body []byte, part int) []textLine {
+	return stringLines(string(body), part)
+}
+
+// stringLines returns the lines of the body of the part with the given index
+// without copying them
+func stringLines(body string, part int) []textLine {
+	lines := make([]textLine, 0, strings.Count(body, "\n")+1)
+	var offset int
+	for {
+		idx := strings.IndexByte(body[offset:], '\n')
+		if idx < 0 {
+			return append(lines, textLine{body[offset:], part, offset})
+		}
+
+		lines = append(lines, textLine{body[offset : offset+idx], part, offset})
+		offset += idx + 1
+	}
+}
+
+// trim returns the line without its leading and trailing white space
+func (l textLine) trim() textLine {
+	text := strings.TrimLeftFunc(l.text, unicode.IsSpace)
+	return textLine{strings.TrimRightFunc(text, unicode.IsSpace), l.part, l.offset + len(l.text) - len(text)}
+}
+
+// location returns the location of the line in the message
+func (l textLine) location() *Location {
+	return &Location{Part: l.part, Offset: l.offset}
+}
+
+// AnalyzeMessage reads a complete raw email message from the given reader and
+// returns the Result of its analysis.
+func AnalyzeMessage(r io.Reader) (Result, error) {
+	m, err := ReadMessage(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return defaultAnalyzer.Analyze(m), nil
+}
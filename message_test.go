@@ -0,0 +1,105 @@
+package bouncespy
+
+import (
+	"strings"
+
+	ch "gopkg.in/check.v1"
+)
+
+var qpMsg = "From: Mail Delivery Subsystem <mailer-daemon@googlemail.com>\r\n" +
+	"To: bar@bar.bar\r\n" +
+	"Subject: Delivery Status Notification (Failure)\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Delivery to the following recipient failed permanently:\r\n" +
+	"\r\n" +
+	"     foo@foo.foo\r\n" +
+	"\r\n" +
+	"Technical details of permanent failure:=20\r\n" +
+	"The error that the other server returned was:\r\n" +
+	"5=\r\n" +
+	"50 5.1.1 The email account that you tried to reach does not exist.\r\n" +
+	"\r\n" +
+	"----- Original message -----\r\n" +
+	"\r\n" +
+	"Foo"
+
+var base64Msg = "From: MAILER-DAEMON@mx1.foo.foo\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"VGhlIHJlYXNvbiBmb3IgdGhlIHByb2JsZW06CjUuMS4wIC0gVW5rbm93biBhZGRyZXNzIGVycm9y\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"UmVwb3J0aW5nLU1UQTogZG5zOyBteDEuZm9vLmZvbwoKRmluYWwtUmVjaXBpZW50OiByZmM4MjI7\r\n" +
+	"IGZvb0Bmb28uZm9vCkFjdGlvbjogZmFpbGVkClN0YXR1czogNS4yLjEK\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"Subject: Foo\r\n" +
+	"\r\n" +
+	"Status: 5.1.1\r\n" +
+	"\r\n" +
+	"--inner--\r\n" +
+	"\r\n" +
+	"--outer--\r\n"
+
+func (s *BounceSuite) TestReadMessage(c *ch.C) {
+	m, err := ReadMessage(strings.NewReader(base64Msg))
+	c.Assert(err, ch.IsNil)
+	c.Assert(m.Header.Get("From"), Equals, "MAILER-DAEMON@mx1.foo.foo")
+	c.Assert(m.Parts, ch.HasLen, 3)
+
+	c.Assert(m.Parts[0].MediaType, Equals, "text/plain")
+	c.Assert(string(m.Parts[0].Body), Equals, "The reason for the problem:\n5.1.0 - Unknown address error")
+	c.Assert(m.Parts[1].MediaType, Equals, "message/delivery-status")
+	c.Assert(string(m.Parts[1].Body), Equals, "Reporting-MTA: dns; mx1.foo.foo\n\nFinal-Recipient: rfc822; foo@foo.foo\nAction: failed\nStatus: 5.2.1\n")
+	c.Assert(m.Parts[2].MediaType, Equals, "message/rfc822")
+
+	m, err = ReadMessage(strings.NewReader(qpMsg))
+	c.Assert(err, ch.IsNil)
+	c.Assert(m.Parts, ch.HasLen, 1)
+	c.Assert(m.Parts[0].Params["charset"], Equals, "UTF-8")
+	c.Assert(strings.Contains(string(m.Parts[0].Body), "failure: \r\n"), Equals, true)
+	c.Assert(strings.Contains(string(m.Parts[0].Body), "\r\n550 5.1.1 The email"), Equals, true)
+}
+
+func (s *BounceSuite) TestReadMessageBrokenMultipart(c *ch.C) {
+	m, err := ReadMessage(strings.NewReader("Content-Type: multipart/mixed; boundary=foo\r\n\r\nStatus: 5.1.1\r\n"))
+	c.Assert(err, ch.IsNil)
+	c.Assert(m.Parts, ch.HasLen, 1)
+	c.Assert(m.Parts[0].MediaType, Equals, "text/plain")
+	c.Assert(string(m.Parts[0].Body), Equals, "Status: 5.1.1\r\n")
+}
+
+func (s *BounceSuite) TestAnalyzeMessage(c *ch.C) {
+	cases := []struct {
+		msg string
+		r   BounceReason
+	}{
+		{qpMsg, BadDestinationMailboxAddress},
+		{base64Msg, MailboxDisabled},
+		{dsnMsg, BadDestinationMailboxAddress},
+		{"Subject: Foo\r\n\r\n" + msg3, OtherAddressError},
+	}
+
+	for _, cs := range cases {
+		result, err := AnalyzeMessage(strings.NewReader(cs.msg))
+		c.Assert(err, ch.IsNil)
+		c.Assert(result.Reason, Equals, cs.r)
+	}
+
+	_, err := AnalyzeMessage(strings.NewReader(""))
+	c.Assert(err, ch.NotNil)
+}
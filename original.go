@@ -0,0 +1,140 @@
+package bouncespy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// OriginalMessage is the message a bounce or complaint is about
+type OriginalMessage struct {
+	// Header contains the headers of the original message. It's nil if the
+	// message was inlined in the text of the bounce without its headers.
+	Header mail.Header `json:"header,omitempty"`
+	// Body is the body of the original message. It's nil if only the headers
+	// were included.
+	Body []byte `json:"body,omitempty"`
+	// Inline reports whether the message was inlined in the text of the
+	// bounce instead of being attached as a MIME part.
+	Inline bool `json:"inline"`
+}
+
+// HeaderValue returns the first value of the given header of the original
+// message with its RFC 2047 encoded words decoded.
+func (o *OriginalMessage) HeaderValue(key string) string {
+	return decodeHeader(o.Header.Get(key))
+}
+
+// originalMarkers are the lowercase lines that precede the original message
+// when it's inlined in the text of a bounce
+var originalMarkers = []string{
+	"----- original message -----",
+	"-----original message-----",
+	"------ this is a copy of the message, including all the headers. ------",
+	"------ this is a copy of the message's headers. ------",
+	"--- below this line is a copy of the message.",
+	"original message follows.",
+}
+
+// originalEndMarker is the start of the lowercase line that ends the inlined
+// original message in some bounces
+const originalEndMarker = "----- end of"
+
+// Original returns the original message included in the message, either as a
+// message/rfc822 or text/rfc822-headers part or inlined in its text after a
+// line such as "----- Original message -----". It returns nil if the message
+// does not include it. The original message is read once and shared by all
+// the calls, so it must not be modified.
+func (m *Message) Original() *OriginalMessage {
+	m.originalOnce.Do(func() {
+		m.original = m.readOriginal()
+	})
+
+	return m.original
+}
+
+func (m *Message) readOriginal() *OriginalMessage {
+	for _, p := range m.Parts {
+		switch p.MediaType {
+		case "message/rfc822":
+			if header, body, err := splitMessage(p.Body); err == nil {
+				return &OriginalMessage{Header: header, Body: body}
+			}
+		case "text/rfc822-headers":
+			if fields, err := readFields(p.Body); err == nil && len(fields) > 0 {
+				return &OriginalMessage{Header: mail.Header(fields)}
+			}
+		}
+	}
+
+	return inlineOriginal(m.scan())
+}
+
+// splitMessage returns the header of the raw message and its body, which is
+// a slice of the message instead of a copy, as it may be large.
+func splitMessage(data []byte) (mail.Header, []byte, error) {
+	br := bytes.NewReader(data)
+	r := bufio.NewReader(br)
+	fields, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil && (err != io.EOF || len(fields) == 0) {
+		return nil, nil, err
+	}
+
+	return mail.Header(fields), data[len(data)-br.Len()-r.Buffered():], nil
+}
+
+// inlineOriginal returns the original message inlined in the text after one
+// of the original markers.
+func inlineOriginal(scan *textScan) *OriginalMessage {
+	lines, lower := scan.lines, scan.lowerLines
+	start := -1
+	for i, line := range lower {
+		if containsAny(strings.TrimSpace(line), originalMarkers) {
+			start = i + 1
+			break
+		}
+	}
+
+	if start < 0 {
+		return nil
+	}
+
+	for start < len(lines) && strings.TrimSpace(lines[start].text) == "" {
+		start++
+	}
+
+	end := len(lines)
+	for i := start; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lower[i]), originalEndMarker) {
+			end = i
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines[start:end] {
+		buf.WriteString(strings.TrimSuffix(line.text, "\r"))
+		buf.WriteByte('\n')
+	}
+
+	content := bytes.TrimSpace(buf.Bytes())
+	if len(content) == 0 {
+		return nil
+	}
+
+	original := &OriginalMessage{Inline: true}
+	header, body, err := splitMessage(append(content, '\n'))
+	if err == nil && len(header) > 0 {
+		original.Header = header
+		if len(bytes.TrimSpace(body)) > 0 {
+			original.Body = body
+		}
+	} else {
+		original.Body = content
+	}
+
+	return original
+}
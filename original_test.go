@@ -0,0 +1,89 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+var eximMsg = `This message was created automatically by mail delivery software.
+
+A message that you sent could not be delivered to one or more of its
+recipients. This is a permanent error. The following address(es) failed:
+
+  foo@foo.foo
+    SMTP error from remote mail server after RCPT TO:<foo@foo.foo>:
+    550 5.1.1 User unknown
+
+------ This is a copy of the message, including all the headers. ------
+
+Message-ID: <1234@bar.bar>
+To: foo@foo.foo
+Subject: =?UTF-8?Q?Caf=C3=A9?=
+X-Campaign-ID: 42
+X-Customer-ID: 7
+
+Hello!
+`
+
+var rfc822HeadersMsg = "Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Delivery failed\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"Message-ID: <1234@bar.bar>\r\n" +
+	"X-Campaign-ID: 42\r\n" +
+	"--b--\r\n"
+
+func (s *BounceSuite) TestOriginalMIMEPart(c *ch.C) {
+	m := NewMessage(readMessage(c, dsnMsg))
+	original := m.Original()
+	c.Assert(original, ch.NotNil)
+	c.Assert(original.Inline, Equals, false)
+	c.Assert(original.Header.Get("Subject"), Equals, "Foo")
+	c.Assert(string(original.Body), Equals, "Foo\r\n")
+	// the original message is only read once
+	c.Assert(m.Original() == original, Equals, true)
+
+	original = NewMessage(readMessage(c, rfc822HeadersMsg)).Original()
+	c.Assert(original, ch.NotNil)
+	c.Assert(original.Header.Get("Message-Id"), Equals, "<1234@bar.bar>")
+	c.Assert(original.Header.Get("X-Campaign-Id"), Equals, "42")
+	c.Assert(original.Body, ch.IsNil)
+}
+
+func (s *BounceSuite) TestOriginalInline(c *ch.C) {
+	original := NewMessage(mail.Header{}, []byte(eximMsg)).Original()
+	c.Assert(original, ch.NotNil)
+	c.Assert(original.Inline, Equals, true)
+	c.Assert(original.Header.Get("Message-Id"), Equals, "<1234@bar.bar>")
+	c.Assert(original.Header.Get("To"), Equals, "foo@foo.foo")
+	c.Assert(original.HeaderValue("Subject"), Equals, "Café")
+	c.Assert(original.Header.Get("X-Campaign-Id"), Equals, "42")
+	c.Assert(original.Header.Get("X-Customer-Id"), Equals, "7")
+	c.Assert(string(original.Body), Equals, "Hello!\n")
+
+	original = NewMessage(mail.Header{}, []byte(msg1)).Original()
+	c.Assert(original, ch.DeepEquals, &OriginalMessage{Body: []byte("Foo"), Inline: true})
+
+	original = NewMessage(readMessage(c, qpMsg)).Original()
+	c.Assert(original, ch.DeepEquals, &OriginalMessage{Body: []byte("Foo"), Inline: true})
+
+	c.Assert(NewMessage(mail.Header{}, []byte(msg2)).Original(), ch.IsNil)
+	c.Assert(NewMessage(mail.Header{}, []byte("----- Original message -----\n\n")).Original(), ch.IsNil)
+}
+
+func (s *BounceSuite) TestAnalyzeOriginal(c *ch.C) {
+	result := Analyze(mail.Header{}, []byte(eximMsg))
+	c.Assert(result.Original, ch.NotNil)
+	c.Assert(result.Original.Header.Get("X-Campaign-Id"), Equals, "42")
+
+	result = Analyze(readMessage(c, arfMsg))
+	c.Assert(result.Original.Header.Get("Subject"), Equals, "Earn money")
+
+	c.Assert(Analyze(mail.Header{}, []byte(msg2)).Original, ch.IsNil)
+}
@@ -0,0 +1,227 @@
+package bouncespy
+
+import "strings"
+
+// phrases are the lowercase phrases used by the bounce messages of a language.
+// Trailing colons are ignored when matching.
+type phrases struct {
+	// lang is the BCP 47 tag of the language.
+	lang string
+	// failed are the phrases that introduce a recipient that failed permanently.
+	failed []string
+	// delayed are the phrases that introduce a recipient whose delivery has
+	// been delayed.
+	delayed []string
+	// markers are the phrases that end the line before the diagnostic of the
+	// remote server.
+	markers []string
+	// notices are other phrases of bounce messages, such as their subjects,
+	// that are only used to detect the language.
+	notices []string
+}
+
+// phraseCatalogue contains the phrases of the supported languages. When a
+// message has as many phrases of several languages, the first one is used.
+var phraseCatalogue = []phrases{
+	{
+		lang:    "en",
+		failed:  []string{deliveryFailedPermanently, "delivery has failed to these recipients or groups"},
+		delayed: []string{deliveryDelayed},
+		markers: []string{errorOtherServerReturned, reasonOfTheProblem, reasonForTheProblem},
+		notices: []string{"undeliverable", "delivery status notification", "mail delivery failed", "returned mail"},
+	},
+	{
+		lang: "de",
+		failed: []string{
+			"die zustellung an den folgenden empfänger ist endgültig fehlgeschlagen",
+			"die nachricht konnte nicht an folgende empfänger zugestellt werden",
+			"fehler bei der zustellung an diese empfänger oder gruppen",
+		},
+		delayed: []string{
+			"die zustellung an den folgenden empfänger wurde verzögert",
+			"zustellung verzögert",
+		},
+		markers: []string{
+			"der vom anderen server zurückgegebene fehler lautet",
+			"der grund für das problem",
+			"fehlermeldung des anderen servers",
+		},
+		notices: []string{"unzustellbar", "nicht zustellbar", "übermittlungsstatusbenachrichtigung"},
+	},
+	{
+		lang: "fr",
+		failed: []string{
+			"la distribution au destinataire suivant a échoué définitivement",
+			"échec de la remise à ces destinataires ou groupes",
+			"votre message n'a pas pu être remis",
+		},
+		delayed: []string{
+			"la distribution au destinataire suivant a été retardée",
+			"remise différée",
+		},
+		markers: []string{
+			"l'erreur renvoyée par l'autre serveur est la suivante",
+			"la raison du problème",
+			"erreur renvoyée par le serveur distant",
+		},
+		notices: []string{"non remis", "non distribuable", "notification d'état de la distribution"},
+	},
+	{
+		lang: "es",
+		failed: []string{
+			"la entrega al siguiente destinatario ha fallado de forma permanente",
+			"error al entregar a estos destinatarios o grupos",
+			"no se ha podido entregar el mensaje",
+		},
+		delayed: []string{
+			"la entrega al siguiente destinatario se ha retrasado",
+			"entrega retrasada",
+		},
+		markers: []string{
+			"el error que ha devuelto el otro servidor es",
+			"el motivo del problema",
+			"la razón del problema",
+		},
+		notices: []string{"no se puede entregar", "no entregado", "notificación de estado de entrega"},
+	},
+	{
+		lang: "pt",
+		failed: []string{
+			"a entrega ao seguinte destinatário falhou permanentemente",
+			"falha na entrega para estes destinatários ou grupos",
+			"não foi possível entregar a mensagem",
+		},
+		delayed: []string{
+			"a entrega ao seguinte destinatário foi adiada",
+			"entrega atrasada",
+		},
+		markers: []string{
+			"o erro retornado pelo outro servidor foi",
+			"o motivo do problema",
+			"a razão do problema",
+		},
+		notices: []string{"não entregue", "não é possível entregar", "notificação de status de entrega"},
+	},
+	{
+		lang: "it",
+		failed: []string{
+			"recapito non riuscito in modo permanente al seguente destinatario",
+			"impossibile recapitare a questi destinatari o gruppi",
+			"non è stato possibile recapitare il messaggio",
+		},
+		delayed: []string{
+			"il recapito al seguente destinatario è stato ritardato",
+			"recapito ritardato",
+		},
+		markers: []string{
+			"l'errore restituito dall'altro server è",
+			"il motivo del problema",
+			"la ragione del problema",
+		},
+		notices: []string{"non recapitabile", "non recapitato", "notifica dello stato di recapito"},
+	},
+	{
+		lang: "ja",
+		failed: []string{
+			"次の受信者への配信に失敗しました",
+			"メッセージを配信できませんでした",
+			"配信できませんでした",
+		},
+		delayed: []string{
+			"次の受信者への配信が遅延しています",
+			"配信が遅れています",
+		},
+		markers: []string{
+			"相手のサーバーから返されたエラー",
+			"問題の原因",
+		},
+		notices: []string{"配信不能", "配信状態の通知"},
+	},
+	{
+		lang: "ru",
+		failed: []string{
+			"не удалось доставить сообщение следующему получателю",
+			"не удалось выполнить доставку следующим получателям или группам",
+			"сообщение не может быть доставлено",
+		},
+		delayed: []string{
+			"доставка сообщения следующему получателю задерживается",
+			"доставка задерживается",
+		},
+		markers: []string{
+			"ошибка, возвращенная другим сервером",
+			"причина проблемы",
+		},
+		notices: []string{"не доставлено", "недоставленное сообщение", "уведомление о состоянии доставки"},
+	},
+}
+
+func failedPhrases(p phrases) []string  { return p.failed }
+func delayedPhrases(p phrases) []string { return p.delayed }
+func markerPhrases(p phrases) []string  { return p.markers }
+func noticePhrases(p phrases) []string  { return p.notices }
+
+// trimColon removes the trailing colons, including full-width ones, of s
+func trimColon(s string) string {
+	return strings.TrimRight(s, ":：")
+}
+
+// containsPhrase reports whether the lowercase line contains any of the
+// phrases of the catalogue selected by fn.
+func containsPhrase(line string, fn func(phrases) []string) bool {
+	for _, p := range phraseCatalogue {
+		for _, phrase := range fn(p) {
+			if strings.Contains(line, trimColon(phrase)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// endsWithMarker reports whether the lowercase line ends with one of the
+// diagnostic markers of the catalogue followed by a colon, which may be
+// preceded by a space as in French.
+func endsWithMarker(line string) bool {
+	trimmed := trimColon(line)
+	if trimmed == line {
+		return false
+	}
+	trimmed = strings.TrimRight(trimmed, " \u00a0")
+
+	for _, p := range phraseCatalogue {
+		for _, marker := range p.markers {
+			if strings.HasSuffix(trimmed, trimColon(marker)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Language returns the BCP 47 tag of the language of the bounce message, such
+// as "de" or "ja", based on the phrases of its subject and text. An empty
+// string is returned if the language is not one of the supported ones.
+func (m *Message) Language() string {
+	subject, text := strings.ToLower(m.HeaderValue("Subject")), m.scan().lower
+
+	var lang string
+	var max int
+	for _, p := range phraseCatalogue {
+		var count int
+		for _, list := range [][]string{p.failed, p.delayed, p.markers, p.notices} {
+			for _, phrase := range list {
+				phrase = trimColon(phrase)
+				count += strings.Count(subject, phrase) + strings.Count(text, phrase)
+			}
+		}
+
+		if count > max {
+			lang, max = p.lang, count
+		}
+	}
+
+	return lang
+}
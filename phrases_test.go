@@ -0,0 +1,97 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+var germanMsg = `Die Zustellung an den folgenden Empfänger ist endgültig fehlgeschlagen:
+
+     foo@foo.foo
+
+Der vom anderen Server zurückgegebene Fehler lautet:
+550 5.1.1 The email account that you tried to reach does not exist.
+`
+
+var japaneseMsg = `次の受信者への配信が遅延しています：
+
+     foo@foo.foo
+
+相手のサーバーから返されたエラー：
+452 4.2.2 Mailbox full
+`
+
+var russianMsg = `Не удалось доставить сообщение следующему получателю:
+
+     foo@foo.foo
+`
+
+func (s *BounceSuite) TestMessageLanguage(c *ch.C) {
+	cases := []struct {
+		subject string
+		body    string
+		lang    string
+	}{
+		{"", msg1, "en"},
+		{"", germanMsg, "de"},
+		{"", japaneseMsg, "ja"},
+		{"", russianMsg, "ru"},
+		{"Non remis : foo", "foo", "fr"},
+		{"No se puede entregar: foo", "foo", "es"},
+		{"Não entregue: foo", "foo", "pt"},
+		{"Non recapitabile: foo", "foo", "it"},
+		{"=?UTF-8?Q?Unzustellbar=3A_foo?=", "foo", "de"},
+		{"Undeliverable: foo", germanMsg, "de"},
+		{"", "foo", ""},
+	}
+
+	for _, cs := range cases {
+		m := NewMessage(mail.Header{"Subject": []string{cs.subject}}, []byte(cs.body))
+		c.Assert(m.Language(), Equals, cs.lang, ch.Commentf("subject: %q", cs.subject))
+	}
+}
+
+func (s *BounceSuite) TestLocalizedDetectors(c *ch.C) {
+	m := NewMessage(mail.Header{}, []byte(germanMsg))
+	c.Assert(detectDiagnosticMarkers(m), ch.DeepEquals, []Candidate{
+		{
+			Reason:     BadDestinationMailboxAddress,
+			Diagnostic: "550 5.1.1 The email account that you tried to reach does not exist.",
+			Confidence: diagnosticMarkerConfidence,
+			Evidence:   "550 5.1.1 The email account that you tried to reach does not exist.",
+			Location:   &Location{Part: 0, Offset: 147},
+		},
+		{
+			Reason:     MailboxUnavailable,
+			Diagnostic: "550 5.1.1 The email account that you tried to reach does not exist.",
+			Confidence: diagnosticMarkerConfidence,
+			Evidence:   "550 5.1.1 The email account that you tried to reach does not exist.",
+			Location:   &Location{Part: 0, Offset: 147},
+		},
+	})
+	c.Assert(detectGmailNotices(m), ch.DeepEquals, []Candidate{
+		{Reason: UndefinedCode, Action: Failed, Confidence: gmailConfidence, Evidence: "Die Zustellung an den folgenden Empfänger ist endgültig fehlgeschlagen:", Location: &Location{0, 0}},
+	})
+
+	m = NewMessage(mail.Header{}, []byte(japaneseMsg))
+	c.Assert(detectDiagnosticMarkers(m), ch.HasLen, 2)
+	c.Assert(detectDiagnosticMarkers(m)[0].Reason, Equals, TransientMailboxFull)
+	c.Assert(detectDiagnosticMarkers(m)[1].Reason, Equals, ActionAbortedInsufficientStorage)
+	soft := Soft
+	c.Assert(detectGmailNotices(m), ch.DeepEquals, []Candidate{
+		{Reason: NotFound, Type: &soft, Action: Delayed, Confidence: gmailConfidence, Evidence: "次の受信者への配信が遅延しています：", Location: &Location{0, 0}},
+	})
+
+	result := Analyze(mail.Header{}, []byte(russianMsg))
+	c.Assert(result.Reason, Equals, UndefinedCode)
+	c.Assert(result.Language, Equals, "ru")
+}
+
+func (s *BounceSuite) TestEndsWithMarker(c *ch.C) {
+	c.Assert(endsWithMarker("the reason of the problem:"), Equals, true)
+	c.Assert(endsWithMarker("the reason of the problem"), Equals, false)
+	c.Assert(endsWithMarker("la raison du problème :"), Equals, true)
+	c.Assert(endsWithMarker("問題の原因："), Equals, true)
+	c.Assert(endsWithMarker("foo:"), Equals, false)
+}
@@ -0,0 +1,55 @@
+package bouncespy
+
+import "net/mail"
+
+// RecipientResult is the result of the analysis for a single recipient of the
+// bounced message.
+type RecipientResult struct {
+	Recipient  string       `json:"recipient,omitempty"`
+	Type       BounceType   `json:"type"`
+	Reason     BounceReason `json:"reason"`
+	Category   Category     `json:"category"`
+	Action     Action       `json:"action,omitempty"`
+	Diagnostic string       `json:"diagnostic,omitempty"`
+	// Blocklist is the blocklist that rejected the message, if any.
+	Blocklist *Blocklist `json:"blocklist,omitempty"`
+	// Confidence is a value between 0 and 1 that tells how sure the detector
+	// that found the reason is about it. It's 0 if no reason was found.
+	Confidence float64 `json:"confidence"`
+	// Evidence tells where the reason was found. It's nil if no reason was
+	// found.
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+// AnalyzeRecipients returns a RecipientResult for every failed or delayed
+// recipient reported in the delivery status part of the message. If the message
+// does not have a delivery status part, a single result with no recipient and
+// the bounce reason found in the body is returned.
+func AnalyzeRecipients(headers mail.Header, body []byte) []RecipientResult {
+	return defaultAnalyzer.AnalyzeRecipients(NewMessage(headers, body))
+}
+
+func newRecipientResult(recipient string, reason BounceReason) RecipientResult {
+	return RecipientResult{
+		Recipient: recipient,
+		Reason:    reason,
+		Type:      statusInfo(reason).Type,
+	}
+}
+
+// isWorseThan reports whether the result is a worse bounce than the other,
+// that is, a hard bounce over a soft one or a more specific reason over a
+// less specific one.
+func (r RecipientResult) isWorseThan(o RecipientResult) bool {
+	if r.Reason == NotFound {
+		return false
+	} else if o.Reason == NotFound {
+		return true
+	}
+
+	if r.Type != o.Type {
+		return r.Type == Hard
+	}
+
+	return r.Reason.Compare(o.Reason) == MoreSpecific
+}
@@ -0,0 +1,108 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"strings"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestAnalyzeRecipients(c *ch.C) {
+	results := AnalyzeRecipients(readMessage(c, dsnMsg))
+	c.Assert(results, ch.DeepEquals, []RecipientResult{
+		{
+			Recipient:  "foo@foo.foo",
+			Type:       Hard,
+			Reason:     BadDestinationMailboxAddress,
+			Category:   CategoryBadMailbox,
+			Action:     "failed",
+			Diagnostic: "550 5.1.1 <foo@foo.foo>: Recipient address rejected: User unknown in virtual mailbox table",
+			Confidence: deliveryStatusConfidence,
+			Evidence:   &Evidence{Detector: DeliveryStatusDetector, Text: "Status: 5.1.1", Part: 1, Offset: 216},
+		},
+		{
+			Recipient:  "baz@foo.foo",
+			Type:       Soft,
+			Reason:     TransientMailboxFull,
+			Category:   CategoryMailboxFull,
+			Action:     "delayed",
+			Diagnostic: "452 4.2.2 Mailbox full",
+			Confidence: deliveryStatusConfidence,
+			Evidence:   &Evidence{Detector: DeliveryStatusDetector, Text: "Status: 4.2.2", Part: 1, Offset: 496},
+		},
+	})
+
+	diagnostic := "550 Account discontinued, cancelled by user"
+	results = AnalyzeRecipients(mail.Header{}, []byte(msg1))
+	c.Assert(results, ch.DeepEquals, []RecipientResult{
+		{
+			Recipient:  "foo@foo.foo",
+			Type:       Hard,
+			Reason:     MailboxUnavailable,
+			Category:   CategoryInactiveMailbox,
+			Action:     Failed,
+			Diagnostic: diagnostic,
+			Confidence: diagnosticMarkerConfidence,
+			Evidence: &Evidence{
+				Detector: DiagnosticMarkerDetector,
+				Text:     diagnostic,
+				Part:     0,
+				Offset:   strings.LastIndex(msg1, diagnostic),
+			},
+		},
+	})
+}
+
+func (s *BounceSuite) TestAnalyzeRecipientsSkipsDelivered(c *ch.C) {
+	msg := strings.Replace(dsnMsg, "Action: failed", "Action: delivered", 1)
+	results := AnalyzeRecipients(readMessage(c, msg))
+	c.Assert(results, ch.HasLen, 1)
+	c.Assert(results[0].Recipient, Equals, "baz@foo.foo")
+
+	result := Analyze(readMessage(c, msg))
+	c.Assert(result.Recipient, Equals, "baz@foo.foo")
+	c.Assert(result.Reason, Equals, TransientMailboxFull)
+	c.Assert(result.Type, Equals, Soft)
+}
+
+func (s *BounceSuite) TestAnalyzeWorstRecipient(c *ch.C) {
+	msg := strings.Replace(dsnMsg, "Final-Recipient: rfc822; foo@foo.foo", "Final-Recipient: rfc822; qux@foo.foo", 1)
+	msg = strings.Replace(msg, "Status: 5.1.1", "Status: 5.2.2", 1)
+	msg = strings.Replace(msg, "Status: 4.2.2", "Status: 5.1.2", 1)
+
+	result := Analyze(readMessage(c, msg))
+	c.Assert(result.Recipient, Equals, "baz@foo.foo")
+	c.Assert(result.Reason, Equals, BadDestinationSystemAddress)
+
+	result = Analyze(readMessage(c, dsnMsg))
+	c.Assert(result.Recipient, Equals, "foo@foo.foo")
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(result.Type, Equals, Hard)
+}
+
+func (s *BounceSuite) TestIsWorseThan(c *ch.C) {
+	cases := []struct {
+		r1, r2 BounceReason
+		worse  bool
+	}{
+		{NotFound, NotFound, false},
+		{NotFound, MailboxFull, false},
+		{MailboxFull, NotFound, true},
+		{MailboxFull, BadDestinationMailboxAddress, false},
+		{BadDestinationMailboxAddress, MailboxFull, true},
+		{MailboxUnavailable, BadDestinationMailboxAddress, false},
+		{BadDestinationMailboxAddress, MailboxUnavailable, true},
+		{MailboxFull, ServiceNotAvailable, true},
+		{TransientMailboxFull, MailboxFull, false},
+		{MailboxFull, TransientMailboxFull, false},
+		{TransientMailboxFull, ActionAbortedInsufficientStorage, true},
+	}
+
+	for _, cs := range cases {
+		c.Assert(
+			newRecipientResult("", cs.r1).isWorseThan(newRecipientResult("", cs.r2)),
+			Equals,
+			cs.worse,
+		)
+	}
+}
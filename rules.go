@@ -0,0 +1,465 @@
+package bouncespy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RulesDetector is the name of the detector of a RuleSet
+const RulesDetector = "rules"
+
+// defaultRuleConfidence is the confidence of the candidates of rules that do
+// not specify one. Rules are meant to fix the classification of the built-in
+// detectors so they are as reliable as a delivery status.
+const defaultRuleConfidence = 1.0
+
+// Rule is a user-defined classification rule. All the conditions in Match must
+// be satisfied for the rule to be applied. Header, body, diagnostic and remote
+// MTA conditions are case-insensitive regular expressions and the recipient
+// domain condition is an exact domain name.
+type Rule struct {
+	Name       string       `json:"name" yaml:"name"`
+	Match      RuleMatch    `json:"match" yaml:"match"`
+	Reason     BounceReason `json:"reason" yaml:"reason"`
+	Type       string       `json:"type,omitempty" yaml:"type,omitempty"`
+	Category   Category     `json:"category,omitempty" yaml:"category,omitempty"`
+	Confidence float64      `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+}
+
+// RuleMatch contains the conditions of a Rule. The diagnostic, remote MTA and
+// recipient domain conditions are matched against every recipient of the
+// delivery status part of the message. If the message does not have one, the
+// diagnostic condition is matched against the body and the remote MTA and
+// recipient domain conditions never match.
+type RuleMatch struct {
+	Headers         map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body            string            `json:"body,omitempty" yaml:"body,omitempty"`
+	Diagnostic      string            `json:"diagnostic,omitempty" yaml:"diagnostic,omitempty"`
+	RemoteMTA       string            `json:"remote_mta,omitempty" yaml:"remote_mta,omitempty"`
+	RecipientDomain string            `json:"recipient_domain,omitempty" yaml:"recipient_domain,omitempty"`
+}
+
+// RuleError is returned when a rule is not valid
+type RuleError struct {
+	// File is the file the rule was loaded from, if any.
+	File string
+	// Index is the position of the rule in its file, starting at 0.
+	Index int
+	Name  string
+	// Field is the field of the rule that is not valid.
+	Field string
+	Err   error
+}
+
+func (e *RuleError) Error() string {
+	var prefix string
+	if e.File != "" {
+		prefix = e.File + ": "
+	}
+
+	return fmt.Sprintf("bouncespy: %srule #%d (%q): %s: %s", prefix, e.Index, e.Name, e.Field, e.Err)
+}
+
+type compiledRule struct {
+	Rule
+	typ             *BounceType
+	headers         []headerMatch
+	body            *regexp.Regexp
+	diagnostic      *regexp.Regexp
+	remoteMTA       *regexp.Regexp
+	recipientDomain string
+}
+
+type headerMatch struct {
+	key string
+	re  *regexp.Regexp
+}
+
+func compileRule(r Rule, file string, index int) (*compiledRule, error) {
+	fail := func(field string, err error) error {
+		return &RuleError{File: file, Index: index, Name: r.Name, Field: field, Err: err}
+	}
+
+	if r.Name == "" {
+		return nil, fail("name", fmt.Errorf("name is required"))
+	}
+
+	if reason, err := parseReason(string(r.Reason)); err != nil || reason == NotFound {
+		return nil, fail("reason", fmt.Errorf("unknown bounce reason %q", string(r.Reason)))
+	}
+
+	if r.Confidence < 0 || r.Confidence > 1 {
+		return nil, fail("confidence", fmt.Errorf("confidence must be between 0 and 1"))
+	} else if r.Confidence == 0 {
+		r.Confidence = defaultRuleConfidence
+	}
+
+	cr := &compiledRule{
+		Rule:            r,
+		recipientDomain: strings.ToLower(strings.TrimSpace(r.Match.RecipientDomain)),
+	}
+
+	if r.Type != "" {
+		typ, err := parseBounceType(r.Type)
+		if err != nil {
+			return nil, fail("type", err)
+		}
+		cr.typ = &typ
+	}
+
+	if r.Category != "" {
+		category, err := parseCategory(string(r.Category))
+		if err != nil {
+			return nil, fail("category", err)
+		}
+		cr.Category = category
+	}
+
+	var err error
+	compile := func(field, expr string) *regexp.Regexp {
+		if expr == "" || err != nil {
+			return nil
+		}
+
+		re, e := regexp.Compile("(?i)" + expr)
+		if e != nil {
+			err = fail(field, e)
+		}
+		return re
+	}
+
+	keys := make([]string, 0, len(r.Match.Headers))
+	for key := range r.Match.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if r.Match.Headers[key] == "" {
+			return nil, fail("match.headers."+key, fmt.Errorf("empty expression"))
+		}
+		cr.headers = append(cr.headers, headerMatch{key, compile("match.headers."+key, r.Match.Headers[key])})
+	}
+	cr.body = compile("match.body", r.Match.Body)
+	cr.diagnostic = compile("match.diagnostic", r.Match.Diagnostic)
+	cr.remoteMTA = compile("match.remote_mta", r.Match.RemoteMTA)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cr.headers) == 0 && cr.body == nil && cr.diagnostic == nil &&
+		cr.remoteMTA == nil && cr.recipientDomain == "" {
+		return nil, fail("match", fmt.Errorf("at least one condition is required"))
+	}
+
+	return cr, nil
+}
+
+// matchMessage reports whether the message level conditions of the rule
+// match and returns the evidence of the match.
+func (r *compiledRule) matchMessage(m *Message, text string) (string, bool) {
+	var evidence string
+	for _, h := range r.headers {
+		value := m.HeaderValue(h.key)
+		if !h.re.MatchString(value) {
+			return "", false
+		}
+		evidence = h.key + ": " + value
+	}
+
+	if r.body != nil {
+		match := r.body.FindString(text)
+		if match == "" && !r.body.MatchString(text) {
+			return "", false
+		}
+		evidence = match
+	}
+
+	return evidence, true
+}
+
+// matchRecipient reports whether the recipient level conditions of the rule
+// match and returns the evidence of the match.
+func (r *compiledRule) matchRecipient(recipient, diagnostic, remoteMTA string) (string, bool) {
+	var evidence string
+	if r.recipientDomain != "" {
+		idx := strings.LastIndex(recipient, "@")
+		if idx < 0 || strings.ToLower(recipient[idx+1:]) != r.recipientDomain {
+			return "", false
+		}
+		evidence = recipient
+	}
+
+	if r.remoteMTA != nil {
+		if !r.remoteMTA.MatchString(remoteMTA) {
+			return "", false
+		}
+		evidence = remoteMTA
+	}
+
+	if r.diagnostic != nil {
+		match := r.diagnostic.FindString(diagnostic)
+		if match == "" && !r.diagnostic.MatchString(diagnostic) {
+			return "", false
+		}
+		evidence = match
+	}
+
+	return evidence, true
+}
+
+func (r *compiledRule) candidate(recipient, evidence string) Candidate {
+	return Candidate{
+		Rule:       r.Name,
+		Recipient:  recipient,
+		Reason:     r.Reason,
+		Type:       r.typ,
+		Category:   r.Category,
+		Confidence: r.Confidence,
+		Evidence:   evidence,
+	}
+}
+
+// RuleSet is a Detector that applies user-defined rules to messages. The
+// rules are applied in order and the candidates of all the matching rules
+// are returned.
+type RuleSet struct {
+	mu       sync.RWMutex
+	files    []string
+	modTimes map[string]time.Time
+	rules    []*compiledRule
+}
+
+// NewRuleSet returns a RuleSet with the given rules or an error if any of
+// them is not valid.
+func NewRuleSet(rules ...Rule) (*RuleSet, error) {
+	compiled, err := compileRules(rules, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &RuleSet{rules: compiled}, nil
+}
+
+// LoadRules returns a RuleSet with the rules in the given files. Files with the
+// .json extension are decoded as JSON and the rest as YAML. Every file must
+// contain a list of rules.
+func LoadRules(files ...string) (*RuleSet, error) {
+	rs := &RuleSet{files: files}
+	if err := rs.Reload(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// Reload loads again the files of the rule set. If any of the files cannot be
+// loaded the current rules are kept.
+func (rs *RuleSet) Reload() error {
+	var rules []*compiledRule
+	modTimes := make(map[string]time.Time)
+	for _, file := range rs.files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		compiled, err := loadRulesFile(file)
+		if err != nil {
+			return err
+		}
+
+		rules = append(rules, compiled...)
+		modTimes[file] = fi.ModTime()
+	}
+
+	rs.mu.Lock()
+	rs.rules = rules
+	rs.modTimes = modTimes
+	rs.mu.Unlock()
+	return nil
+}
+
+// Watch checks every interval whether the files of the rule set have changed
+// and reloads them if they did. Errors reloading the files are passed to
+// onError, which may be nil. Calling the returned function stops watching.
+func (rs *RuleSet) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !rs.changed() {
+					continue
+				}
+
+				if err := rs.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+func (rs *RuleSet) changed() bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	for _, file := range rs.files {
+		fi, err := os.Stat(file)
+		if err != nil || !fi.ModTime().Equal(rs.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Rules returns the rules of the rule set
+func (rs *RuleSet) Rules() []Rule {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	rules := make([]Rule, len(rs.rules))
+	for i, r := range rs.rules {
+		rules[i] = r.Rule
+	}
+
+	return rules
+}
+
+// Name returns the name of the detector
+func (rs *RuleSet) Name() string {
+	return RulesDetector
+}
+
+// Detect returns a candidate for every rule that matches the message
+func (rs *RuleSet) Detect(m *Message) []Candidate {
+	rs.mu.RLock()
+	rules := rs.rules
+	rs.mu.RUnlock()
+
+	text := m.text()
+	ds, err := m.DeliveryStatus()
+
+	var candidates []Candidate
+	for _, r := range rules {
+		evidence, ok := r.matchMessage(m, text)
+		if !ok {
+			continue
+		}
+
+		if err != nil {
+			if r.remoteMTA != nil || r.recipientDomain != "" {
+				continue
+			}
+
+			if e, ok := r.matchRecipient("", text, ""); ok {
+				candidates = append(candidates, r.candidate("", firstNonEmpty(e, evidence)))
+			}
+			continue
+		}
+
+		for _, rcpt := range failedRecipients(ds) {
+			addr := normalizeAddress(rcpt.FinalRecipient)
+			if e, ok := r.matchRecipient(addr, rcpt.DiagnosticCode, rcpt.RemoteMTA); ok {
+				candidates = append(candidates, r.candidate(addr, firstNonEmpty(e, evidence)))
+			}
+		}
+	}
+
+	return candidates
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func compileRules(rules []Rule, file string) ([]*compiledRule, error) {
+	compiled := make([]*compiledRule, len(rules))
+	for i, r := range rules {
+		cr, err := compileRule(r, file, i)
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = cr
+	}
+
+	return compiled, nil
+}
+
+func loadRulesFile(file string) ([]*compiledRule, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded []ruleFile
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&decoded)
+	} else {
+		err = yaml.UnmarshalStrict(data, &decoded)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("bouncespy: %s: %s", file, err)
+	}
+
+	rules := make([]Rule, len(decoded))
+	for i, r := range decoded {
+		rules[i] = r.rule()
+	}
+
+	return compileRules(rules, file)
+}
+
+// ruleFile is a Rule as it is decoded from a file. The reason and category
+// are decoded as strings so they are validated by compileRule, which returns
+// a RuleError that tells which rule is not valid.
+type ruleFile struct {
+	Name       string    `json:"name" yaml:"name"`
+	Match      RuleMatch `json:"match" yaml:"match"`
+	Reason     string    `json:"reason" yaml:"reason"`
+	Type       string    `json:"type,omitempty" yaml:"type,omitempty"`
+	Category   string    `json:"category,omitempty" yaml:"category,omitempty"`
+	Confidence float64   `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+}
+
+func (r ruleFile) rule() Rule {
+	return Rule{
+		Name:       r.Name,
+		Match:      r.Match,
+		Reason:     BounceReason(r.Reason),
+		Type:       r.Type,
+		Category:   Category(r.Category),
+		Confidence: r.Confidence,
+	}
+}
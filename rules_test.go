@@ -0,0 +1,258 @@
+package bouncespy
+
+import (
+	"io/ioutil"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestNewRuleSetValidation(c *ch.C) {
+	valid := RuleMatch{Body: "foo"}
+	cases := []struct {
+		rule  Rule
+		field string
+	}{
+		{Rule{Match: valid, Reason: MailboxFull}, "name"},
+		{Rule{Name: "a", Match: valid, Reason: "foo"}, "reason"},
+		{Rule{Name: "a", Match: valid}, "reason"},
+		{Rule{Name: "a", Match: valid, Reason: " 5.1.1"}, "reason"},
+		{Rule{Name: "a", Match: valid, Reason: MailboxFull, Type: "medium"}, "type"},
+		{Rule{Name: "a", Match: valid, Reason: MailboxFull, Category: "inactive"}, "category"},
+		{Rule{Name: "a", Match: valid, Reason: MailboxFull, Confidence: 2}, "confidence"},
+		{Rule{Name: "a", Match: RuleMatch{Body: "("}, Reason: MailboxFull}, "match.body"},
+		{Rule{Name: "a", Match: RuleMatch{Diagnostic: "[a"}, Reason: MailboxFull}, "match.diagnostic"},
+		{Rule{Name: "a", Match: RuleMatch{RemoteMTA: "a)"}, Reason: MailboxFull}, "match.remote_mta"},
+		{Rule{Name: "a", Match: RuleMatch{Headers: map[string]string{"Subject": "("}}, Reason: MailboxFull}, "match.headers.Subject"},
+		{Rule{Name: "a", Match: RuleMatch{Headers: map[string]string{"Subject": ""}}, Reason: MailboxFull}, "match.headers.Subject"},
+		{Rule{Name: "a", Reason: MailboxFull}, "match"},
+	}
+
+	for _, cs := range cases {
+		_, err := NewRuleSet(Rule{Name: "ok", Match: valid, Reason: MailboxFull}, cs.rule)
+		c.Assert(err, ch.FitsTypeOf, &RuleError{})
+		rerr := err.(*RuleError)
+		c.Assert(rerr.Index, Equals, 1)
+		c.Assert(rerr.Field, Equals, cs.field)
+		c.Assert(rerr.Name, Equals, cs.rule.Name)
+	}
+
+	rs, err := NewRuleSet(Rule{Name: "a", Match: valid, Reason: "5.7.500", Type: "Hard", Category: "Mailbox_Full"})
+	c.Assert(err, ch.IsNil)
+	c.Assert(rs.Rules(), ch.HasLen, 1)
+	c.Assert(rs.Rules()[0].Confidence, Equals, defaultRuleConfidence)
+	c.Assert(rs.Rules()[0].Category, Equals, CategoryMailboxFull)
+}
+
+func (s *BounceSuite) TestRuleError(c *ch.C) {
+	err := &RuleError{File: "rules.yml", Index: 2, Name: "foo", Field: "reason", Err: os.ErrInvalid}
+	c.Assert(err.Error(), Equals, `bouncespy: rules.yml: rule #2 ("foo"): reason: invalid argument`)
+
+	err.File = ""
+	c.Assert(err.Error(), Equals, `bouncespy: rule #2 ("foo"): reason: invalid argument`)
+}
+
+func (s *BounceSuite) TestRuleSetDetect(c *ch.C) {
+	rs, err := NewRuleSet(
+		Rule{
+			Name:     "quota",
+			Match:    RuleMatch{Body: `over\s+quota`},
+			Reason:   MailboxFull,
+			Category: "mailbox_full",
+		},
+		Rule{
+			Name:   "subject",
+			Match:  RuleMatch{Headers: map[string]string{"Subject": "^undeliverable"}, Diagnostic: "account discontinued"},
+			Reason: MailboxDisabled,
+			Type:   "hard",
+		},
+		Rule{
+			Name:   "domain",
+			Match:  RuleMatch{RecipientDomain: "FOO.foo", RemoteMTA: `^mx2\.`},
+			Reason: BadDestinationSystemAddress,
+		},
+	)
+	c.Assert(err, ch.IsNil)
+	c.Assert(rs.Name(), Equals, RulesDetector)
+
+	m := NewMessage(mail.Header{}, []byte("foo@foo.foo is OVER  quota"))
+	c.Assert(rs.Detect(m), ch.DeepEquals, []Candidate{
+		{Rule: "quota", Reason: MailboxFull, Category: "mailbox_full", Confidence: 1, Evidence: "OVER  quota"},
+	})
+
+	hard := Hard
+	m = NewMessage(mail.Header{"Subject": []string{"Undeliverable: foo"}}, []byte(msg1))
+	c.Assert(rs.Detect(m), ch.DeepEquals, []Candidate{
+		{Rule: "subject", Reason: MailboxDisabled, Type: &hard, Confidence: 1, Evidence: "Account discontinued"},
+	})
+
+	m = NewMessage(readMessage(c, dsnMsg))
+	c.Assert(rs.Detect(m), ch.DeepEquals, []Candidate{
+		{Rule: "domain", Recipient: "foo@foo.foo", Reason: BadDestinationSystemAddress, Confidence: 1, Evidence: "mx2.foo.foo"},
+	})
+}
+
+func (s *BounceSuite) TestRuleSetAnalyzer(c *ch.C) {
+	rs, err := NewRuleSet(Rule{
+		Name:     "discontinued",
+		Match:    RuleMatch{Diagnostic: "account discontinued"},
+		Reason:   MailboxDisabled,
+		Type:     "hard",
+		Category: CategoryInactiveMailbox,
+	})
+	c.Assert(err, ch.IsNil)
+
+	a := NewAnalyzer(append([]Detector{rs}, DefaultDetectors()...)...)
+	result := a.Analyze(NewMessage(mail.Header{}, []byte(msg1)))
+	c.Assert(result.Reason, Equals, MailboxDisabled)
+	c.Assert(result.Type, Equals, Hard)
+	c.Assert(result.Category, Equals, CategoryInactiveMailbox)
+
+	result = a.Analyze(NewMessage(mail.Header{}, []byte(msg2)))
+	c.Assert(result.Reason, Equals, AddressDoesntExist)
+	c.Assert(result.Category, Equals, CategoryBadMailbox)
+
+	// the rules override the reason of the recipients of the delivery status
+	rs, err = NewRuleSet(Rule{
+		Name:   "unknown",
+		Match:  RuleMatch{Diagnostic: "user unknown", RecipientDomain: "foo.foo"},
+		Reason: MailboxUnavailable,
+	})
+	c.Assert(err, ch.IsNil)
+
+	header, body := readMessage(c, strings.Replace(dsnMsg, "rfc822; foo@foo.foo", "rfc822; <Foo@FOO.foo>", 1))
+	results := NewAnalyzer(append([]Detector{rs}, DefaultDetectors()...)...).AnalyzeRecipients(NewMessage(header, body))
+	c.Assert(results, ch.HasLen, 2)
+	c.Assert(results[0].Recipient, Equals, "Foo@foo.foo")
+	c.Assert(results[0].Reason, Equals, MailboxUnavailable)
+	c.Assert(results[0].Evidence.Rule, Equals, "unknown")
+	c.Assert(results[1].Recipient, Equals, "baz@foo.foo")
+	c.Assert(rs.Detect(NewMessage(header, body)), ch.DeepEquals, []Candidate{{
+		Rule:       "unknown",
+		Recipient:  "Foo@foo.foo",
+		Reason:     MailboxUnavailable,
+		Confidence: 1,
+		Evidence:   "User unknown",
+	}})
+}
+
+const yamlRules = `
+- name: quota
+  match:
+    body: over quota
+  reason: 5.2.2
+  category: mailbox_full
+- name: domain
+  match:
+    recipient_domain: foo.foo
+    headers:
+      From: mailer-daemon
+  reason: "5.1.2"
+  type: hard
+  confidence: 0.5
+`
+
+const jsonRules = `[
+	{"name": "quota", "match": {"body": "over quota"}, "reason": "4.2.2", "type": "soft"}
+]`
+
+func writeRules(c *ch.C, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0644), ch.IsNil)
+	return path
+}
+
+func (s *BounceSuite) TestLoadRules(c *ch.C) {
+	dir := c.MkDir()
+	rs, err := LoadRules(
+		writeRules(c, dir, "rules.yml", yamlRules),
+		writeRules(c, dir, "rules.json", jsonRules),
+	)
+	c.Assert(err, ch.IsNil)
+	c.Assert(rs.Rules(), ch.DeepEquals, []Rule{
+		{Name: "quota", Match: RuleMatch{Body: "over quota"}, Reason: MailboxFull, Category: "mailbox_full", Confidence: 1},
+		{
+			Name:       "domain",
+			Match:      RuleMatch{RecipientDomain: "foo.foo", Headers: map[string]string{"From": "mailer-daemon"}},
+			Reason:     BadDestinationSystemAddress,
+			Type:       "hard",
+			Confidence: 0.5,
+		},
+		{Name: "quota", Match: RuleMatch{Body: "over quota"}, Reason: TransientMailboxFull, Type: "soft", Confidence: 1},
+	})
+
+	candidates := rs.Detect(NewMessage(readMessage(c, dsnMsg)))
+	c.Assert(candidates, ch.HasLen, 2)
+	c.Assert(candidates[0].Recipient, Equals, "foo@foo.foo")
+	c.Assert(candidates[1].Recipient, Equals, "baz@foo.foo")
+}
+
+func (s *BounceSuite) TestLoadRulesErrors(c *ch.C) {
+	dir := c.MkDir()
+
+	_, err := LoadRules(filepath.Join(dir, "missing.yml"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	path := writeRules(c, dir, "unknown.yml", "- name: foo\n  reazon: 5.1.1\n")
+	_, err = LoadRules(path)
+	c.Assert(err, ch.ErrorMatches, "(?s).*unknown.yml.*reazon.*")
+
+	path = writeRules(c, dir, "unknown.json", `[{"name": "foo", "reazon": "5.1.1"}]`)
+	_, err = LoadRules(path)
+	c.Assert(err, ch.ErrorMatches, ".*unknown.json.*reazon.*")
+
+	path = writeRules(c, dir, "invalid.yml", yamlRules+"- name: bar\n  match:\n    body: '('\n  reason: 5.1.1\n")
+	_, err = LoadRules(path)
+	c.Assert(err, ch.FitsTypeOf, &RuleError{})
+	c.Assert(err.(*RuleError).File, Equals, path)
+	c.Assert(err.(*RuleError).Index, Equals, 2)
+	c.Assert(err.(*RuleError).Name, Equals, "bar")
+	c.Assert(err.(*RuleError).Field, Equals, "match.body")
+}
+
+func (s *BounceSuite) TestRuleSetWatch(c *ch.C) {
+	dir := c.MkDir()
+	path := writeRules(c, dir, "rules.yml", yamlRules)
+	rs, err := LoadRules(path)
+	c.Assert(err, ch.IsNil)
+	c.Assert(rs.Rules(), ch.HasLen, 2)
+
+	errors := make(chan error, 10)
+	stop := rs.Watch(5*time.Millisecond, func(err error) { errors <- err })
+	defer stop()
+
+	touch := func(content string) {
+		writeRules(c, dir, "rules.yml", content)
+		future := time.Now().Add(time.Hour)
+		c.Assert(os.Chtimes(path, future, future), ch.IsNil)
+	}
+
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				c.Fatal("timed out waiting for the rules to be reloaded")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	touch(strings.Replace(yamlRules, "name: quota", "name: quota2", 1))
+	waitFor(func() bool { return rs.Rules()[0].Name == "quota2" })
+
+	touch("- name: foo\n")
+	select {
+	case err := <-errors:
+		c.Assert(err, ch.FitsTypeOf, &RuleError{})
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the reload error")
+	}
+	c.Assert(rs.Rules()[0].Name, Equals, "quota2")
+
+	stop()
+	stop()
+}
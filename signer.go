@@ -0,0 +1,248 @@
+package bouncespy
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureStatus tells whether the return path a bounce was sent to was
+// signed by a Signer, that is, whether the bounced message was sent by us
+type SignatureStatus int
+
+const (
+	// SignatureUnchecked is the status of bounces analyzed without a Signer.
+	SignatureUnchecked SignatureStatus = iota
+	SignatureValid
+	// SignatureMissing is the status of bounces sent to return paths that
+	// are not signed, which are usually backscatter of forged messages.
+	SignatureMissing
+	SignatureExpired
+	// SignatureForged is the status of bounces sent to return paths with a
+	// signature that does not match.
+	SignatureForged
+)
+
+var signatureStatusNames = map[SignatureStatus]string{
+	SignatureUnchecked: "unchecked",
+	SignatureValid:     "valid",
+	SignatureMissing:   "missing",
+	SignatureExpired:   "expired",
+	SignatureForged:    "forged",
+}
+
+func (s SignatureStatus) String() string {
+	return signatureStatusNames[s]
+}
+
+const (
+	batvPrefix          = "prvs="
+	defaultSignerPrefix = "bounces"
+	defaultSignerMaxAge = 7 * 24 * time.Hour
+	// ownSignatureLength is the number of hex digits of the signatures of
+	// the return paths of the own format.
+	ownSignatureLength = 16
+)
+
+// Signer signs and verifies return paths so bounces to addresses that were
+// not used by us can be told apart. Two formats are supported:
+//
+// BATV return paths, see https://tools.ietf.org/html/draft-levine-smtp-batv-01,
+// which keep the address of the sender, such as prvs=0123abcdef=alice@example.com.
+//
+// Return paths of the own format, which encode the recipient of the message
+// like a VERP, such as bounces+1a2b3c4d5e6f7a8b.pxyz12+bob=example.net@example.com.
+type Signer struct {
+	// Key is the secret key used to sign the return paths.
+	Key []byte
+	// KeyID is the number between 0 and 9 of the key in BATV tags.
+	KeyID int
+	// Prefix is the local part of the return paths of the own format before
+	// the signature.
+	Prefix string
+	// Domain is the domain of the return paths of the own format.
+	Domain string
+	// MaxAge is the time a signature is valid for. BATV signatures are valid
+	// for whole days.
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// NewSigner returns a Signer with the given key whose return paths of the own
+// format use the given domain. Signatures are valid for a week.
+func NewSigner(key []byte, domain string) *Signer {
+	return &Signer{
+		Key:    key,
+		Prefix: defaultSignerPrefix,
+		Domain: domain,
+		MaxAge: defaultSignerMaxAge,
+	}
+}
+
+func (s *Signer) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+
+	return time.Now()
+}
+
+func (s *Signer) maxAge() time.Duration {
+	if s.MaxAge <= 0 {
+		return defaultSignerMaxAge
+	}
+
+	return s.MaxAge
+}
+
+// BATV returns the BATV return path of the given sender address
+func (s *Signer) BATV(sender string) string {
+	day := batvDay(s.time().Add(s.maxAge()))
+	tag := fmt.Sprintf("%d%03d", s.KeyID%10, day)
+	return batvPrefix + tag + s.batvHash(tag, sender) + "=" + sender
+}
+
+// batvDay returns the day number of the given time as used in BATV tags
+func batvDay(t time.Time) int {
+	return int(t.Unix()/(24*60*60)) % 1000
+}
+
+func (s *Signer) batvHash(tag, sender string) string {
+	mac := hmac.New(sha1.New, s.Key)
+	mac.Write([]byte(tag + strings.ToLower(sender)))
+	return hex.EncodeToString(mac.Sum(nil))[:6]
+}
+
+// ReturnPath returns the signed return path of the own format for a message
+// sent to the given recipient
+func (s *Signer) ReturnPath(recipient string) string {
+	ts := strconv.FormatInt(s.time().Unix(), 36)
+	encoded := strings.Replace(recipient, "@", "=", -1)
+	return s.Prefix + "+" + s.ownHash(ts, encoded) + "." + ts + "+" + encoded + "@" + s.Domain
+}
+
+func (s *Signer) ownHash(ts, encoded string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(ts + "+" + strings.ToLower(encoded)))
+	return hex.EncodeToString(mac.Sum(nil))[:ownSignatureLength]
+}
+
+// Verify verifies the signature of the given return path and returns the
+// address in it, which is the sender for BATV return paths and the recipient
+// for return paths of the own format. SignatureMissing is returned for
+// return paths that are not signed.
+func (s *Signer) Verify(returnPath string) (SignatureStatus, string) {
+	returnPath = strings.Trim(strings.TrimSpace(returnPath), "<>")
+	if isBATV(returnPath) {
+		return s.verifyBATV(returnPath[len(batvPrefix):])
+	}
+
+	idx := strings.LastIndex(returnPath, "@")
+	if idx < 0 || !strings.HasPrefix(strings.ToLower(returnPath[:idx]), strings.ToLower(s.Prefix+"+")) {
+		return SignatureMissing, ""
+	}
+
+	return s.verifyOwn(returnPath[len(s.Prefix)+1 : idx])
+}
+
+// verifyBATV verifies the BATV tag-val=loc-core@domain return path
+func (s *Signer) verifyBATV(rest string) (SignatureStatus, string) {
+	idx := strings.Index(rest, "=")
+	if idx != 10 {
+		return SignatureMissing, ""
+	}
+
+	tag, hash, sender := rest[:4], strings.ToLower(rest[4:10]), rest[11:]
+	if tag[0]-'0' != byte(s.KeyID%10) {
+		return SignatureForged, sender
+	}
+
+	if !hmac.Equal([]byte(hash), []byte(s.batvHash(tag, sender))) {
+		return SignatureForged, sender
+	}
+
+	expiry, err := strconv.Atoi(tag[1:])
+	if err != nil {
+		return SignatureForged, sender
+	}
+
+	// days are numbered modulo 1000, so the expiry day must be between today
+	// and the maximum age of the signatures
+	days := (expiry - batvDay(s.time()) + 1000) % 1000
+	if days > int(s.maxAge()/(24*time.Hour))+1 {
+		return SignatureExpired, sender
+	}
+
+	return SignatureValid, sender
+}
+
+// verifyOwn verifies the hash.timestamp+local=domain local part of a return
+// path of the own format
+func (s *Signer) verifyOwn(local string) (SignatureStatus, string) {
+	parts := strings.SplitN(local, "+", 2)
+	if len(parts) != 2 {
+		return SignatureMissing, ""
+	}
+
+	sig := strings.SplitN(parts[0], ".", 2)
+	if len(sig) != 2 || len(sig[0]) != ownSignatureLength {
+		return SignatureMissing, ""
+	}
+
+	encoded := parts[1]
+	recipient := encoded
+	if idx := strings.LastIndex(encoded, "="); idx >= 0 {
+		recipient = encoded[:idx] + "@" + encoded[idx+1:]
+	}
+
+	if !hmac.Equal([]byte(strings.ToLower(sig[0])), []byte(s.ownHash(strings.ToLower(sig[1]), encoded))) {
+		return SignatureForged, recipient
+	}
+
+	ts, err := strconv.ParseInt(sig[1], 36, 64)
+	if err != nil {
+		return SignatureForged, recipient
+	}
+
+	if s.time().Sub(time.Unix(ts, 0)) > s.maxAge() {
+		return SignatureExpired, recipient
+	}
+
+	return SignatureValid, recipient
+}
+
+// VerifyMessage verifies the return path the bounce was sent to, which is
+// looked for in the X-Original-To, Delivered-To, Envelope-To and To headers.
+// The status of the first signed return path found is returned, along with
+// the address in it.
+func (s *Signer) VerifyMessage(m *Message) (SignatureStatus, string) {
+	status, _, addr := s.verifyMessage(m)
+	return status, addr
+}
+
+// verifyMessage is like VerifyMessage but it also returns the return path
+func (s *Signer) verifyMessage(m *Message) (SignatureStatus, string, string) {
+	for _, key := range verpHeaders {
+		for _, value := range m.Header[textproto.CanonicalMIMEHeaderKey(key)] {
+			for _, address := range headerAddresses(value) {
+				if status, addr := s.Verify(address); status != SignatureMissing {
+					return status, address, addr
+				}
+			}
+		}
+	}
+
+	return SignatureMissing, "", ""
+}
+
+// isBATV reports whether the return path is a BATV one
+func isBATV(returnPath string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.Trim(strings.TrimSpace(returnPath), "<>")), batvPrefix)
+}
@@ -0,0 +1,127 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+
+	ch "gopkg.in/check.v1"
+)
+
+func newTestSigner(now time.Time) *Signer {
+	s := NewSigner([]byte("secret"), "ourdomain.com")
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func (s *BounceSuite) TestSignerBATV(c *ch.C) {
+	now := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	signer := newTestSigner(now)
+	signer.KeyID = 3
+
+	returnPath := signer.BATV("alice@ourdomain.com")
+	c.Assert(strings.HasPrefix(returnPath, "prvs=3"), Equals, true)
+	c.Assert(strings.HasSuffix(returnPath, "=alice@ourdomain.com"), Equals, true)
+	c.Assert(returnPath, ch.HasLen, len("prvs=3DDDSSSSSS=alice@ourdomain.com"))
+
+	status, sender := signer.Verify(returnPath)
+	c.Assert(status, Equals, SignatureValid)
+	c.Assert(sender, Equals, "alice@ourdomain.com")
+
+	status, _ = signer.Verify("<" + strings.ToUpper(returnPath[:15]) + returnPath[15:] + ">")
+	c.Assert(status, Equals, SignatureValid)
+
+	status, _ = signer.Verify(strings.Replace(returnPath, "alice", "bob", 1))
+	c.Assert(status, Equals, SignatureForged)
+
+	other := newTestSigner(now)
+	other.Key = []byte("other")
+	other.KeyID = 3
+	status, _ = other.Verify(returnPath)
+	c.Assert(status, Equals, SignatureForged)
+
+	later := newTestSigner(now.Add(9 * 24 * time.Hour))
+	later.KeyID = 3
+	status, _ = later.Verify(returnPath)
+	c.Assert(status, Equals, SignatureExpired)
+
+	status, _ = signer.Verify("prvs=foo=alice@ourdomain.com")
+	c.Assert(status, Equals, SignatureMissing)
+}
+
+func (s *BounceSuite) TestSignerReturnPath(c *ch.C) {
+	now := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	signer := newTestSigner(now)
+
+	returnPath := signer.ReturnPath("bob@example.net")
+	c.Assert(strings.HasPrefix(returnPath, "bounces+"), Equals, true)
+	c.Assert(strings.HasSuffix(returnPath, "+bob=example.net@ourdomain.com"), Equals, true)
+
+	status, rcpt := signer.Verify(returnPath)
+	c.Assert(status, Equals, SignatureValid)
+	c.Assert(rcpt, Equals, "bob@example.net")
+
+	status, _ = signer.Verify(strings.ToLower(returnPath))
+	c.Assert(status, Equals, SignatureValid)
+
+	status, rcpt = signer.Verify(strings.Replace(returnPath, "bob", "eve", 1))
+	c.Assert(status, Equals, SignatureForged)
+	c.Assert(rcpt, Equals, "eve@example.net")
+
+	status, _ = newTestSigner(now.Add(8 * 24 * time.Hour)).Verify(returnPath)
+	c.Assert(status, Equals, SignatureExpired)
+
+	for _, addr := range []string{"bob@example.net", "bounces@ourdomain.com", "bounces+bob=example.net@ourdomain.com", "foo"} {
+		status, _ = signer.Verify(addr)
+		c.Assert(status, Equals, SignatureMissing, ch.Commentf("address: %s", addr))
+	}
+}
+
+func (s *BounceSuite) TestSignatureStatusString(c *ch.C) {
+	c.Assert(SignatureUnchecked.String(), Equals, "unchecked")
+	c.Assert(SignatureValid.String(), Equals, "valid")
+	c.Assert(SignatureMissing.String(), Equals, "missing")
+	c.Assert(SignatureExpired.String(), Equals, "expired")
+	c.Assert(SignatureForged.String(), Equals, "forged")
+}
+
+func (s *BounceSuite) TestAnalyzeSignatures(c *ch.C) {
+	signer := NewSigner([]byte("secret"), "ourdomain.com")
+	a := NewAnalyzer()
+	a.VerifySignatures(signer)
+
+	header := mail.Header{
+		"To":           {"Our Bounces <" + signer.ReturnPath("bob@example.net") + ">"},
+		"Delivered-To": {"bounces@ourdomain.com"},
+	}
+	result := a.Analyze(NewMessage(header, []byte("foo")))
+	c.Assert(result.Signature, Equals, SignatureValid)
+	c.Assert(result.Recipient, Equals, "bob@example.net")
+
+	result = a.Analyze(NewMessage(header, []byte(msg1)))
+	c.Assert(result.Signature, Equals, SignatureValid)
+	c.Assert(result.Reason, Equals, MailboxUnavailable)
+	c.Assert(result.Recipient, Equals, "bob@example.net")
+
+	// the signed recipient wins over the ones named in the message
+	results := a.AnalyzeRecipients(NewMessage(header, []byte(msg5)))
+	c.Assert(results, ch.HasLen, 1)
+	c.Assert(results[0].Recipient, Equals, "bob@example.net")
+	c.Assert(results[0].Reason, Equals, BadDestinationMailboxAddress)
+
+	// BATV return paths contain the sender, so the recipient of the message
+	// is used
+	header = mail.Header{"To": {signer.BATV("alice@ourdomain.com")}}
+	result = a.Analyze(NewMessage(header, []byte(msg1)))
+	c.Assert(result.Signature, Equals, SignatureValid)
+	c.Assert(result.Recipient, Equals, "foo@foo.foo")
+
+	result = a.Analyze(NewMessage(header, []byte(msg3)))
+	c.Assert(result.Recipient, Equals, "")
+
+	header = mail.Header{"To": {"alice@ourdomain.com"}}
+	result = a.Analyze(NewMessage(header, []byte(msg1)))
+	c.Assert(result.Signature, Equals, SignatureMissing)
+
+	c.Assert(Analyze(header, []byte(msg1)).Signature, Equals, SignatureUnchecked)
+}
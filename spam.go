@@ -0,0 +1,292 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Names of the spam scanners of the entries of a SpamReport
+const (
+	ScannerSpamAssassin = "spamassassin"
+	ScannerRspamd       = "rspamd"
+	ScannerExchange     = "exchange"
+	ScannerBarracuda    = "barracuda"
+	ScannerProofpoint   = "proofpoint"
+)
+
+// SpamEntry is the spam score found in a header
+type SpamEntry struct {
+	// Header is the name of the header the entry was found in.
+	Header  string `json:"header"`
+	Scanner string `json:"scanner"`
+	// Original reports whether the header is one of the original message
+	// instead of the bounce.
+	Original bool    `json:"original"`
+	Score    float64 `json:"score"`
+	// Required is the score from which messages are considered spam. It's
+	// only valid if HasRequired is true.
+	Required    float64 `json:"required"`
+	HasRequired bool    `json:"has_required"`
+	// Spam is the verdict of the scanner. It's only valid if HasVerdict is
+	// true.
+	Spam       bool `json:"spam"`
+	HasVerdict bool `json:"has_verdict"`
+	// Tests are the names of the tests or symbols that matched.
+	Tests []string `json:"tests,omitempty"`
+}
+
+// SpamReport contains the spam scores found in the headers of a message
+type SpamReport struct {
+	// Present reports whether any spam score was found.
+	Present bool `json:"present"`
+	// Score is the score of the first entry, or 0 if there are none.
+	Score   float64     `json:"score"`
+	Entries []SpamEntry `json:"entries,omitempty"`
+}
+
+// Entry returns the first entry found in the given header
+func (r SpamReport) Entry(header string) (SpamEntry, bool) {
+	for _, e := range r.Entries {
+		if strings.EqualFold(e.Header, header) {
+			return e, true
+		}
+	}
+
+	return SpamEntry{}, false
+}
+
+type spamHeaderParser struct {
+	header  string
+	scanner string
+	parse   func(value string) (SpamEntry, bool)
+}
+
+// spamHeaderParsers are the parsers of the spam headers in the order their
+// entries are added to the reports
+var spamHeaderParsers = []spamHeaderParser{
+	{"X-Spam-Status", ScannerSpamAssassin, parseSpamStatus},
+	{"X-Spam-Score", ScannerSpamAssassin, parseSpamNumber},
+	{"X-Spam-Level", ScannerSpamAssassin, parseSpamLevel},
+	{"X-Spamd-Result", ScannerRspamd, parseSpamdResult},
+	{"X-MS-Exchange-Organization-SCL", ScannerExchange, parseSpamNumber},
+	{"X-Microsoft-Antispam", ScannerExchange, parseMicrosoftAntispam},
+	{"X-Barracuda-Spam-Status", ScannerBarracuda, parseSpamStatus},
+	{"X-Barracuda-Spam-Score", ScannerBarracuda, parseSpamNumber},
+	{"X-Proofpoint-Spam-Details", ScannerProofpoint, parseProofpointDetails},
+}
+
+// ParseSpamReport returns the spam scores found in the given headers, such as
+// the ones of SpamAssassin, Rspamd, Exchange, Barracuda or Proofpoint.
+func ParseSpamReport(headers mail.Header) SpamReport {
+	var r SpamReport
+	r.add(headers, false)
+	return r
+}
+
+// SpamReport returns the spam scores found in the headers of the message and
+// in the ones of the original message, if it's included.
+func (m *Message) SpamReport() SpamReport {
+	r := ParseSpamReport(m.Header)
+	if original := m.Original(); original != nil {
+		r.add(original.Header, true)
+	}
+
+	return r
+}
+
+func (r *SpamReport) add(headers mail.Header, original bool) {
+	for _, p := range spamHeaderParsers {
+		// the header may be present but empty, as X-Spam-Level when the score
+		// is below 1
+		values := headers[textproto.CanonicalMIMEHeaderKey(p.header)]
+		if len(values) == 0 {
+			continue
+		}
+
+		entry, ok := p.parse(strings.TrimSpace(values[0]))
+		if !ok {
+			continue
+		}
+
+		entry.Header = p.header
+		entry.Scanner = p.scanner
+		entry.Original = original
+		if !r.Present {
+			r.Present = true
+			r.Score = entry.Score
+		}
+		r.Entries = append(r.Entries, entry)
+	}
+}
+
+func parseSpamNumber(value string) (SpamEntry, bool) {
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return SpamEntry{}, false
+	}
+
+	return SpamEntry{Score: score}, true
+}
+
+// parseSpamLevel parses the stars of X-Spam-Level, one for every point of the
+// score. An empty header is a score below 1.
+func parseSpamLevel(value string) (SpamEntry, bool) {
+	if strings.Trim(value, "*+") != "" {
+		return SpamEntry{}, false
+	}
+
+	return SpamEntry{Score: float64(len(value))}, true
+}
+
+// parseSpamStatus parses the X-Spam-Status header of SpamAssassin, such as
+// "Yes, score=5.2 required=5.0 tests=BAYES_99,HTML_MESSAGE autolearn=no", and
+// the similar X-Barracuda-Spam-Status header.
+func parseSpamStatus(value string) (SpamEntry, bool) {
+	var entry SpamEntry
+	var hasScore bool
+
+	verdict := value
+	if idx := strings.IndexAny(value, ", "); idx >= 0 {
+		verdict, value = value[:idx], value[idx+1:]
+	}
+
+	switch strings.ToLower(verdict) {
+	case "yes":
+		entry.Spam, entry.HasVerdict = true, true
+	case "no":
+		entry.HasVerdict = true
+	}
+
+	var inTests bool
+	for _, token := range strings.Fields(value) {
+		idx := strings.Index(token, "=")
+		if idx < 0 {
+			if inTests {
+				entry.Tests = appendTests(entry.Tests, token)
+			}
+			continue
+		}
+
+		key, v := strings.ToLower(token[:idx]), token[idx+1:]
+		inTests = key == "tests"
+		switch key {
+		case "score", "hits":
+			if score, err := strconv.ParseFloat(v, 64); err == nil {
+				entry.Score, hasScore = score, true
+			}
+		case "required", "kill_level":
+			if required, err := strconv.ParseFloat(v, 64); err == nil {
+				entry.Required, entry.HasRequired = required, true
+			}
+		case "tests":
+			if !strings.EqualFold(v, "none") {
+				entry.Tests = appendTests(entry.Tests, v)
+			}
+		}
+	}
+
+	return entry, hasScore
+}
+
+func appendTests(tests []string, value string) []string {
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tests = append(tests, t)
+		}
+	}
+
+	return tests
+}
+
+// parseSpamdResult parses the X-Spamd-Result header of Rspamd, such as
+// "default: False [3.40 / 15.00]; SYMBOL(1.00)[options]; ..."
+func parseSpamdResult(value string) (SpamEntry, bool) {
+	segments := strings.Split(value, ";")
+	head := segments[0]
+
+	open, close := strings.Index(head, "["), strings.Index(head, "]")
+	if open < 0 || close < open {
+		return SpamEntry{}, false
+	}
+
+	scores := strings.Split(head[open+1:close], "/")
+	score, err := strconv.ParseFloat(strings.TrimSpace(scores[0]), 64)
+	if err != nil {
+		return SpamEntry{}, false
+	}
+
+	entry := SpamEntry{Score: score}
+	if len(scores) > 1 {
+		if required, err := strconv.ParseFloat(strings.TrimSpace(scores[1]), 64); err == nil {
+			entry.Required, entry.HasRequired = required, true
+		}
+	}
+
+	fields := strings.Fields(head[:open])
+	if len(fields) > 0 {
+		switch strings.ToLower(fields[len(fields)-1]) {
+		case "true":
+			entry.Spam, entry.HasVerdict = true, true
+		case "false":
+			entry.HasVerdict = true
+		}
+	}
+
+	for _, s := range segments[1:] {
+		s = strings.TrimSpace(s)
+		if idx := strings.IndexAny(s, "(["); idx >= 0 {
+			s = s[:idx]
+		}
+
+		if s != "" {
+			entry.Tests = append(entry.Tests, s)
+		}
+	}
+
+	return entry, true
+}
+
+// parseMicrosoftAntispam parses the bulk complaint level of the
+// X-Microsoft-Antispam header, such as "BCL:0;"
+func parseMicrosoftAntispam(value string) (SpamEntry, bool) {
+	for _, field := range strings.Split(value, ";") {
+		field = strings.TrimSpace(field)
+		if len(field) > 4 && strings.EqualFold(field[:4], "bcl:") {
+			return parseSpamNumber(strings.TrimSpace(field[4:]))
+		}
+	}
+
+	return SpamEntry{}, false
+}
+
+// parseProofpointDetails parses the X-Proofpoint-Spam-Details header, such
+// as "rule=notspam policy=default score=0 spamscore=0 ..."
+func parseProofpointDetails(value string) (SpamEntry, bool) {
+	fields := make(map[string]string)
+	for _, token := range strings.Fields(value) {
+		if idx := strings.Index(token, "="); idx > 0 {
+			fields[strings.ToLower(token[:idx])] = token[idx+1:]
+		}
+	}
+
+	score, ok := fields["spamscore"]
+	if !ok {
+		score = fields["score"]
+	}
+
+	entry, ok := parseSpamNumber(score)
+	if !ok {
+		return SpamEntry{}, false
+	}
+
+	switch strings.ToLower(fields["rule"]) {
+	case "spam":
+		entry.Spam, entry.HasVerdict = true, true
+	case "notspam":
+		entry.HasVerdict = true
+	}
+
+	return entry, true
+}
@@ -0,0 +1,123 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"net/textproto"
+
+	ch "gopkg.in/check.v1"
+)
+
+var spamOriginalMsg = `The following address(es) failed:
+
+  foo@foo.foo
+    550 5.7.1 Message rejected as spam
+
+------ This is a copy of the message, including all the headers. ------
+
+Message-ID: <1234@bar.bar>
+X-Spam-Status: Yes, score=7.1 required=5.0 tests=BAYES_99,
+	HTML_MESSAGE autolearn=no version=3.4.2
+
+Hello!
+`
+
+func (s *BounceSuite) TestParseSpamReport(c *ch.C) {
+	cases := []struct {
+		header string
+		value  string
+		entry  SpamEntry
+	}{
+		{
+			"X-Spam-Status",
+			"Yes, score=7.1 required=5.0 tests=BAYES_99,HTML_MESSAGE autolearn=no",
+			SpamEntry{Score: 7.1, Required: 5, HasRequired: true, Spam: true, HasVerdict: true, Tests: []string{"BAYES_99", "HTML_MESSAGE"}},
+		},
+		{
+			"X-Spam-Status",
+			"No, hits=-1.2 required=5.0 tests=none",
+			SpamEntry{Score: -1.2, Required: 5, HasRequired: true, HasVerdict: true},
+		},
+		{"X-Spam-Score", "-4.0", SpamEntry{Score: -4}},
+		{"X-Spam-Level", "***", SpamEntry{Score: 3}},
+		{"X-Spam-Level", "", SpamEntry{}},
+		{
+			"X-Spamd-Result",
+			"default: False [3.40 / 15.00]; R_SPF_ALLOW(-0.20)[+ip4:1.1.1.1]; BAYES_HAM(-3.00)[99.99%]",
+			SpamEntry{Score: 3.4, Required: 15, HasRequired: true, HasVerdict: true, Tests: []string{"R_SPF_ALLOW", "BAYES_HAM"}},
+		},
+		{"X-MS-Exchange-Organization-SCL", "5", SpamEntry{Score: 5}},
+		{"X-Microsoft-Antispam", "BCL:7;", SpamEntry{Score: 7}},
+		{
+			"X-Barracuda-Spam-Status",
+			"Yes, SCORE=6.50 using global scores of TAG_LEVEL=3.5 QUARANTINE_LEVEL=1000.0 KILL_LEVEL=5.0 tests=BSF_SC0_MISMATCH_TO",
+			SpamEntry{Score: 6.5, Required: 5, HasRequired: true, Spam: true, HasVerdict: true, Tests: []string{"BSF_SC0_MISMATCH_TO"}},
+		},
+		{"X-Barracuda-Spam-Score", "6.50", SpamEntry{Score: 6.5}},
+		{
+			"X-Proofpoint-Spam-Details",
+			"rule=spam policy=default score=100 spamscore=98 ipscore=0",
+			SpamEntry{Score: 98, Spam: true, HasVerdict: true},
+		},
+	}
+
+	for _, cs := range cases {
+		report := ParseSpamReport(mail.Header{textproto.CanonicalMIMEHeaderKey(cs.header): {cs.value}})
+		c.Assert(report.Present, Equals, true, ch.Commentf("header: %s", cs.header))
+		c.Assert(report.Score, Equals, cs.entry.Score)
+		c.Assert(report.Entries, ch.HasLen, 1)
+
+		entry := report.Entries[0]
+		c.Assert(entry.Header, Equals, cs.header)
+		c.Assert(entry.Scanner, ch.Not(Equals), "")
+		entry.Header, entry.Scanner = "", ""
+		c.Assert(entry, ch.DeepEquals, cs.entry, ch.Commentf("header: %s", cs.header))
+	}
+}
+
+func (s *BounceSuite) TestParseSpamReportInvalid(c *ch.C) {
+	report := ParseSpamReport(mail.Header{
+		"X-Spam-Score":         {"foo"},
+		"X-Spam-Level":         {"foo"},
+		"X-Spamd-Result":       {"default: False"},
+		"X-Microsoft-Antispam": {"BCL:;"},
+	})
+	c.Assert(report.Present, Equals, false)
+	c.Assert(report.Entries, ch.HasLen, 0)
+}
+
+func (s *BounceSuite) TestSpamReportEntry(c *ch.C) {
+	report := ParseSpamReport(mail.Header{
+		"X-Spam-Score":                   {"2.5"},
+		"X-Ms-Exchange-Organization-Scl": {"1"},
+	})
+	c.Assert(report.Score, Equals, 2.5)
+	c.Assert(report.Entries, ch.HasLen, 2)
+
+	entry, ok := report.Entry("x-ms-exchange-organization-scl")
+	c.Assert(ok, Equals, true)
+	c.Assert(entry.Scanner, Equals, ScannerExchange)
+	c.Assert(entry.Score, Equals, 1.0)
+
+	_, ok = report.Entry("X-Spamd-Result")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *BounceSuite) TestMessageSpamReport(c *ch.C) {
+	m := NewMessage(mail.Header{"X-Spam-Score": {"0.1"}}, []byte(spamOriginalMsg))
+	report := m.SpamReport()
+	c.Assert(report.Present, Equals, true)
+	c.Assert(report.Score, Equals, 0.1)
+	c.Assert(report.Entries, ch.HasLen, 2)
+	c.Assert(report.Entries[0].Original, Equals, false)
+
+	entry := report.Entries[1]
+	c.Assert(entry.Original, Equals, true)
+	c.Assert(entry.Spam, Equals, true)
+	c.Assert(entry.Score, Equals, 7.1)
+	c.Assert(entry.Tests, ch.DeepEquals, []string{"BAYES_99", "HTML_MESSAGE"})
+
+	result := Analyze(mail.Header{}, []byte(spamOriginalMsg))
+	c.Assert(result.SpamScore, Equals, 0.0)
+	c.Assert(result.Spam.Present, Equals, true)
+	c.Assert(result.Spam.Score, Equals, 7.1)
+}
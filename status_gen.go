@@ -0,0 +1,569 @@
+// Code generated by gen_status.go. DO NOT EDIT.
+
+package bouncespy
+
+// StatusMap is a map indexed by bounce reason that returns its StatusInfo.
+// Enhanced status codes that are not in the map use the info of the closest
+// known code, see EnhancedStatusCode.KnownReason.
+var StatusMap = map[BounceReason]StatusInfo{
+	"421":    {Soft, false},
+	"432":    {Soft, false},
+	"450":    {Soft, false},
+	"451":    {Soft, false},
+	"452":    {Soft, false},
+	"454":    {Soft, false},
+	"455":    {Soft, false},
+	"500":    {Hard, false},
+	"501":    {Hard, false},
+	"502":    {Hard, false},
+	"503":    {Hard, false},
+	"504":    {Hard, false},
+	"521":    {Hard, false},
+	"530":    {Hard, false},
+	"534":    {Hard, false},
+	"535":    {Hard, false},
+	"538":    {Hard, false},
+	"550":    {Hard, false},
+	"551":    {Hard, false},
+	"552":    {Hard, false},
+	"553":    {Hard, false},
+	"554":    {Hard, false},
+	"555":    {Hard, false},
+	"556":    {Hard, false},
+	"9.1.1":  {Hard, true},
+	"4.0.0":  {Soft, true},
+	"4.1.0":  {Soft, true},
+	"4.1.1":  {Soft, true},
+	"4.1.2":  {Soft, true},
+	"4.1.3":  {Soft, true},
+	"4.1.4":  {Soft, true},
+	"4.1.5":  {Soft, true},
+	"4.1.6":  {Soft, true},
+	"4.1.7":  {Soft, true},
+	"4.1.8":  {Soft, true},
+	"4.1.9":  {Soft, true},
+	"4.1.10": {Soft, true},
+	"4.2.0":  {Soft, true},
+	"4.2.1":  {Soft, true},
+	"4.2.2":  {Soft, true},
+	"4.2.3":  {Soft, true},
+	"4.2.4":  {Soft, true},
+	"4.3.0":  {Soft, true},
+	"4.3.1":  {Soft, true},
+	"4.3.2":  {Soft, true},
+	"4.3.3":  {Soft, true},
+	"4.3.4":  {Soft, true},
+	"4.3.5":  {Soft, true},
+	"4.3.6":  {Soft, true},
+	"4.4.0":  {Soft, true},
+	"4.4.1":  {Soft, true},
+	"4.4.2":  {Soft, true},
+	"4.4.3":  {Soft, true},
+	"4.4.4":  {Soft, true},
+	"4.4.5":  {Soft, true},
+	"4.4.6":  {Soft, true},
+	"4.4.7":  {Soft, true},
+	"4.5.0":  {Soft, true},
+	"4.5.1":  {Soft, true},
+	"4.5.2":  {Soft, true},
+	"4.5.3":  {Soft, true},
+	"4.5.4":  {Soft, true},
+	"4.5.5":  {Soft, true},
+	"4.5.6":  {Soft, true},
+	"4.6.0":  {Soft, true},
+	"4.6.1":  {Soft, true},
+	"4.6.2":  {Soft, true},
+	"4.6.3":  {Soft, true},
+	"4.6.4":  {Soft, true},
+	"4.6.5":  {Soft, true},
+	"4.6.6":  {Soft, true},
+	"4.6.7":  {Soft, true},
+	"4.6.8":  {Soft, true},
+	"4.6.9":  {Soft, true},
+	"4.6.10": {Soft, true},
+	"4.7.0":  {Soft, true},
+	"4.7.1":  {Soft, true},
+	"4.7.2":  {Soft, true},
+	"4.7.3":  {Soft, true},
+	"4.7.4":  {Soft, true},
+	"4.7.5":  {Soft, true},
+	"4.7.6":  {Soft, true},
+	"4.7.7":  {Soft, true},
+	"4.7.8":  {Soft, true},
+	"4.7.9":  {Soft, true},
+	"4.7.10": {Soft, true},
+	"4.7.11": {Soft, true},
+	"4.7.12": {Soft, true},
+	"4.7.13": {Soft, true},
+	"4.7.14": {Soft, true},
+	"4.7.15": {Soft, true},
+	"4.7.16": {Soft, true},
+	"4.7.17": {Soft, true},
+	"4.7.18": {Soft, true},
+	"4.7.19": {Soft, true},
+	"4.7.20": {Soft, true},
+	"4.7.21": {Soft, true},
+	"4.7.22": {Soft, true},
+	"4.7.23": {Soft, true},
+	"4.7.24": {Soft, true},
+	"4.7.25": {Soft, true},
+	"4.7.26": {Soft, true},
+	"4.7.27": {Soft, true},
+	"4.7.29": {Soft, true},
+	"4.7.30": {Soft, true},
+	"5.0.0":  {Hard, true},
+	"5.1.0":  {Hard, true},
+	"5.1.1":  {Hard, true},
+	"5.1.2":  {Hard, true},
+	"5.1.3":  {Hard, true},
+	"5.1.4":  {Hard, true},
+	"5.1.5":  {Hard, true},
+	"5.1.6":  {Hard, true},
+	"5.1.7":  {Hard, true},
+	"5.1.8":  {Hard, true},
+	"5.1.9":  {Hard, true},
+	"5.1.10": {Hard, true},
+	"5.2.0":  {Soft, true},
+	"5.2.1":  {Soft, true},
+	"5.2.2":  {Soft, true},
+	"5.2.3":  {Hard, true},
+	"5.2.4":  {Hard, true},
+	"5.3.0":  {Hard, true},
+	"5.3.1":  {Soft, true},
+	"5.3.2":  {Hard, true},
+	"5.3.3":  {Hard, true},
+	"5.3.4":  {Hard, true},
+	"5.3.5":  {Hard, true},
+	"5.3.6":  {Hard, true},
+	"5.4.0":  {Hard, true},
+	"5.4.1":  {Hard, true},
+	"5.4.2":  {Hard, true},
+	"5.4.3":  {Hard, true},
+	"5.4.4":  {Hard, true},
+	"5.4.5":  {Soft, true},
+	"5.4.6":  {Hard, true},
+	"5.4.7":  {Hard, true},
+	"5.5.0":  {Hard, true},
+	"5.5.1":  {Hard, true},
+	"5.5.2":  {Hard, true},
+	"5.5.3":  {Soft, true},
+	"5.5.4":  {Hard, true},
+	"5.5.5":  {Hard, true},
+	"5.5.6":  {Hard, true},
+	"5.6.0":  {Hard, true},
+	"5.6.1":  {Hard, true},
+	"5.6.2":  {Hard, true},
+	"5.6.3":  {Hard, true},
+	"5.6.4":  {Hard, true},
+	"5.6.5":  {Hard, true},
+	"5.6.6":  {Hard, true},
+	"5.6.7":  {Hard, true},
+	"5.6.8":  {Hard, true},
+	"5.6.9":  {Hard, true},
+	"5.6.10": {Hard, true},
+	"5.7.0":  {Hard, true},
+	"5.7.1":  {Hard, true},
+	"5.7.2":  {Hard, true},
+	"5.7.3":  {Hard, true},
+	"5.7.4":  {Hard, true},
+	"5.7.5":  {Hard, true},
+	"5.7.6":  {Hard, true},
+	"5.7.7":  {Hard, true},
+	"5.7.8":  {Hard, true},
+	"5.7.9":  {Hard, true},
+	"5.7.10": {Hard, true},
+	"5.7.11": {Hard, true},
+	"5.7.12": {Hard, true},
+	"5.7.13": {Hard, true},
+	"5.7.14": {Hard, true},
+	"5.7.15": {Hard, true},
+	"5.7.16": {Hard, true},
+	"5.7.17": {Hard, true},
+	"5.7.18": {Hard, true},
+	"5.7.19": {Hard, true},
+	"5.7.20": {Hard, true},
+	"5.7.21": {Hard, true},
+	"5.7.22": {Hard, true},
+	"5.7.23": {Hard, true},
+	"5.7.24": {Hard, true},
+	"5.7.25": {Hard, true},
+	"5.7.26": {Hard, true},
+	"5.7.27": {Hard, true},
+	"5.7.29": {Hard, true},
+	"5.7.30": {Hard, true},
+}
+
+var reasonDescriptions = map[BounceReason]string{
+	"421":    "service not available",
+	"432":    "a password transition is needed",
+	"450":    "mail action not taken: mailbox unavailable",
+	"451":    "action aborted: error in processing",
+	"452":    "action aborted: insufficient system storage",
+	"454":    "temporary authentication failure or TLS not available",
+	"455":    "the server is unable to accommodate the parameters",
+	"500":    "the server could not recognize the command due to a syntax error",
+	"501":    "a syntax error was encountered in command arguments",
+	"502":    "this command is not implemented",
+	"503":    "the server has encountered a bad sequence of commands",
+	"504":    "a command parameter is not implemented",
+	"521":    "the host does not accept mail",
+	"530":    "authentication is required",
+	"534":    "the authentication mechanism is too weak",
+	"535":    "the authentication credentials are invalid",
+	"538":    "encryption is required for the requested authentication mechanism",
+	"550":    "user's mailbox was unavailable (such as not found)",
+	"551":    "the recipient is not local to the server",
+	"552":    "the action was aborted due to exceeded storage allocation",
+	"553":    "the command was aborted because the mailbox name is invalid",
+	"554":    "the transaction failed for some unstated reason",
+	"555":    "the MAIL FROM or RCPT TO parameters are not recognized or not implemented",
+	"556":    "the domain does not accept mail",
+	"9.1.1":  "hard bounce with no bounce code found",
+	"4.0.0":  "other undefined status",
+	"4.1.0":  "other address status",
+	"4.1.1":  "bad destination mailbox address",
+	"4.1.2":  "bad destination system address",
+	"4.1.3":  "bad destination mailbox address syntax",
+	"4.1.4":  "destination mailbox address ambiguous",
+	"4.1.5":  "destination address valid",
+	"4.1.6":  "destination mailbox has moved, no forwarding address",
+	"4.1.7":  "bad sender's mailbox address syntax",
+	"4.1.8":  "bad sender's system address",
+	"4.1.9":  "message relayed to non-compliant mailer",
+	"4.1.10": "recipient address has null MX",
+	"4.2.0":  "other or undefined mailbox status",
+	"4.2.1":  "mailbox disabled, not accepting messages",
+	"4.2.2":  "mailbox full",
+	"4.2.3":  "message length exceeds administrative limit",
+	"4.2.4":  "mailing list expansion problem",
+	"4.3.0":  "other or undefined mail system status",
+	"4.3.1":  "mail system full",
+	"4.3.2":  "system not accepting network messages",
+	"4.3.3":  "system not capable of selected features",
+	"4.3.4":  "message too big for system",
+	"4.3.5":  "system incorrectly configured",
+	"4.3.6":  "requested priority was changed",
+	"4.4.0":  "other or undefined network or routing status",
+	"4.4.1":  "no answer from host",
+	"4.4.2":  "bad connection",
+	"4.4.3":  "directory server failure",
+	"4.4.4":  "unable to route",
+	"4.4.5":  "mail system congestion",
+	"4.4.6":  "routing loop detected",
+	"4.4.7":  "delivery time expired",
+	"4.5.0":  "other or undefined protocol status",
+	"4.5.1":  "invalid command",
+	"4.5.2":  "syntax error",
+	"4.5.3":  "too many recipients",
+	"4.5.4":  "invalid command arguments",
+	"4.5.5":  "wrong protocol version",
+	"4.5.6":  "authentication exchange line is too long",
+	"4.6.0":  "other or undefined media error",
+	"4.6.1":  "media not supported",
+	"4.6.2":  "conversion required and prohibited",
+	"4.6.3":  "conversion required but not supported",
+	"4.6.4":  "conversion with loss performed",
+	"4.6.5":  "conversion failed",
+	"4.6.6":  "message content not available",
+	"4.6.7":  "non-ASCII addresses not permitted for that sender/recipient",
+	"4.6.8":  "UTF-8 string reply is required, but not permitted by the SMTP client",
+	"4.6.9":  "UTF-8 header message cannot be transferred to one or more recipients, so the message must be rejected",
+	"4.6.10": "UTF-8 string reply is required, but not permitted by the SMTP client",
+	"4.7.0":  "other or undefined security status",
+	"4.7.1":  "delivery not authorized, message refused",
+	"4.7.2":  "mailing list expansion prohibited",
+	"4.7.3":  "security conversion required but not possible",
+	"4.7.4":  "security features not supported",
+	"4.7.5":  "cryptographic failure",
+	"4.7.6":  "cryptographic algorithm not supported",
+	"4.7.7":  "message integrity failure",
+	"4.7.8":  "authentication credentials invalid",
+	"4.7.9":  "authentication mechanism is too weak",
+	"4.7.10": "encryption needed",
+	"4.7.11": "encryption required for requested authentication mechanism",
+	"4.7.12": "a password transition is needed",
+	"4.7.13": "user account disabled",
+	"4.7.14": "trust relationship required",
+	"4.7.15": "priority level is too low",
+	"4.7.16": "message is too big for the specified priority",
+	"4.7.17": "mailbox owner has changed",
+	"4.7.18": "domain owner has changed",
+	"4.7.19": "RRVS test cannot be completed",
+	"4.7.20": "no passing DKIM signature found",
+	"4.7.21": "no acceptable DKIM signature found",
+	"4.7.22": "no valid author-matched DKIM signature found",
+	"4.7.23": "SPF validation failed",
+	"4.7.24": "SPF validation error",
+	"4.7.25": "reverse DNS validation failed",
+	"4.7.26": "multiple authentication checks failed",
+	"4.7.27": "sender address has null MX",
+	"4.7.29": "ARC validation failure",
+	"4.7.30": "REQUIRETLS support required",
+	"5.0.0":  "other undefined status",
+	"5.1.0":  "other address status",
+	"5.1.1":  "bad destination mailbox address",
+	"5.1.2":  "bad destination system address",
+	"5.1.3":  "bad destination mailbox address syntax",
+	"5.1.4":  "destination mailbox address ambiguous",
+	"5.1.5":  "destination address valid",
+	"5.1.6":  "destination mailbox has moved, no forwarding address",
+	"5.1.7":  "bad sender's mailbox address syntax",
+	"5.1.8":  "bad sender's system address",
+	"5.1.9":  "message relayed to non-compliant mailer",
+	"5.1.10": "recipient address has null MX",
+	"5.2.0":  "other or undefined mailbox status",
+	"5.2.1":  "mailbox disabled, not accepting messages",
+	"5.2.2":  "mailbox full",
+	"5.2.3":  "message length exceeds administrative limit",
+	"5.2.4":  "mailing list expansion problem",
+	"5.3.0":  "other or undefined mail system status",
+	"5.3.1":  "mail system full",
+	"5.3.2":  "system not accepting network messages",
+	"5.3.3":  "system not capable of selected features",
+	"5.3.4":  "message too big for system",
+	"5.3.5":  "system incorrectly configured",
+	"5.3.6":  "requested priority was changed",
+	"5.4.0":  "other or undefined network or routing status",
+	"5.4.1":  "no answer from host",
+	"5.4.2":  "bad connection",
+	"5.4.3":  "directory server failure",
+	"5.4.4":  "unable to route",
+	"5.4.5":  "mail system congestion",
+	"5.4.6":  "routing loop detected",
+	"5.4.7":  "delivery time expired",
+	"5.5.0":  "other or undefined protocol status",
+	"5.5.1":  "invalid command",
+	"5.5.2":  "syntax error",
+	"5.5.3":  "too many recipients",
+	"5.5.4":  "invalid command arguments",
+	"5.5.5":  "wrong protocol version",
+	"5.5.6":  "authentication exchange line is too long",
+	"5.6.0":  "other or undefined media error",
+	"5.6.1":  "media not supported",
+	"5.6.2":  "conversion required and prohibited",
+	"5.6.3":  "conversion required but not supported",
+	"5.6.4":  "conversion with loss performed",
+	"5.6.5":  "conversion failed",
+	"5.6.6":  "message content not available",
+	"5.6.7":  "non-ASCII addresses not permitted for that sender/recipient",
+	"5.6.8":  "UTF-8 string reply is required, but not permitted by the SMTP client",
+	"5.6.9":  "UTF-8 header message cannot be transferred to one or more recipients, so the message must be rejected",
+	"5.6.10": "UTF-8 string reply is required, but not permitted by the SMTP client",
+	"5.7.0":  "other or undefined security status",
+	"5.7.1":  "delivery not authorized, message refused",
+	"5.7.2":  "mailing list expansion prohibited",
+	"5.7.3":  "security conversion required but not possible",
+	"5.7.4":  "security features not supported",
+	"5.7.5":  "cryptographic failure",
+	"5.7.6":  "cryptographic algorithm not supported",
+	"5.7.7":  "message integrity failure",
+	"5.7.8":  "authentication credentials invalid",
+	"5.7.9":  "authentication mechanism is too weak",
+	"5.7.10": "encryption needed",
+	"5.7.11": "encryption required for requested authentication mechanism",
+	"5.7.12": "a password transition is needed",
+	"5.7.13": "user account disabled",
+	"5.7.14": "trust relationship required",
+	"5.7.15": "priority level is too low",
+	"5.7.16": "message is too big for the specified priority",
+	"5.7.17": "mailbox owner has changed",
+	"5.7.18": "domain owner has changed",
+	"5.7.19": "RRVS test cannot be completed",
+	"5.7.20": "no passing DKIM signature found",
+	"5.7.21": "no acceptable DKIM signature found",
+	"5.7.22": "no valid author-matched DKIM signature found",
+	"5.7.23": "SPF validation failed",
+	"5.7.24": "SPF validation error",
+	"5.7.25": "reverse DNS validation failed",
+	"5.7.26": "multiple authentication checks failed",
+	"5.7.27": "sender address has null MX",
+	"5.7.29": "ARC validation failure",
+	"5.7.30": "REQUIRETLS support required",
+}
+
+var reasonReferences = map[BounceReason]string{
+	"421":    "RFC5321",
+	"432":    "RFC4954",
+	"450":    "RFC5321",
+	"451":    "RFC5321",
+	"452":    "RFC5321",
+	"454":    "RFC4954",
+	"455":    "RFC5321",
+	"500":    "RFC5321",
+	"501":    "RFC5321",
+	"502":    "RFC5321",
+	"503":    "RFC5321",
+	"504":    "RFC5321",
+	"521":    "RFC7504",
+	"530":    "RFC4954",
+	"534":    "RFC4954",
+	"535":    "RFC4954",
+	"538":    "RFC4954",
+	"550":    "RFC5321",
+	"551":    "RFC5321",
+	"552":    "RFC5321",
+	"553":    "RFC5321",
+	"554":    "RFC5321",
+	"555":    "RFC5321",
+	"556":    "RFC7504",
+	"4.0.0":  "RFC3463",
+	"4.1.0":  "RFC3463",
+	"4.1.1":  "RFC3463",
+	"4.1.2":  "RFC3463",
+	"4.1.3":  "RFC3463",
+	"4.1.4":  "RFC3463",
+	"4.1.5":  "RFC3463",
+	"4.1.6":  "RFC3463",
+	"4.1.7":  "RFC3463",
+	"4.1.8":  "RFC3463",
+	"4.1.9":  "RFC3886",
+	"4.1.10": "RFC7505",
+	"4.2.0":  "RFC3463",
+	"4.2.1":  "RFC3463",
+	"4.2.2":  "RFC3463",
+	"4.2.3":  "RFC3463",
+	"4.2.4":  "RFC3463",
+	"4.3.0":  "RFC3463",
+	"4.3.1":  "RFC3463",
+	"4.3.2":  "RFC3463",
+	"4.3.3":  "RFC3463",
+	"4.3.4":  "RFC3463",
+	"4.3.5":  "RFC3463",
+	"4.3.6":  "RFC6710",
+	"4.4.0":  "RFC3463",
+	"4.4.1":  "RFC3463",
+	"4.4.2":  "RFC3463",
+	"4.4.3":  "RFC3463",
+	"4.4.4":  "RFC3463",
+	"4.4.5":  "RFC3463",
+	"4.4.6":  "RFC3463",
+	"4.4.7":  "RFC3463",
+	"4.5.0":  "RFC3463",
+	"4.5.1":  "RFC3463",
+	"4.5.2":  "RFC3463",
+	"4.5.3":  "RFC3463",
+	"4.5.4":  "RFC3463",
+	"4.5.5":  "RFC3463",
+	"4.5.6":  "RFC4954",
+	"4.6.0":  "RFC3463",
+	"4.6.1":  "RFC3463",
+	"4.6.2":  "RFC3463",
+	"4.6.3":  "RFC3463",
+	"4.6.4":  "RFC3463",
+	"4.6.5":  "RFC3463",
+	"4.6.6":  "RFC4468",
+	"4.6.7":  "RFC6531",
+	"4.6.8":  "RFC6531",
+	"4.6.9":  "RFC6531",
+	"4.6.10": "RFC6531",
+	"4.7.0":  "RFC3463",
+	"4.7.1":  "RFC3463",
+	"4.7.2":  "RFC3463",
+	"4.7.3":  "RFC3463",
+	"4.7.4":  "RFC3463",
+	"4.7.5":  "RFC3463",
+	"4.7.6":  "RFC3463",
+	"4.7.7":  "RFC3463",
+	"4.7.8":  "RFC4954",
+	"4.7.9":  "RFC4954",
+	"4.7.10": "RFC5248",
+	"4.7.11": "RFC4954",
+	"4.7.12": "RFC4954",
+	"4.7.13": "RFC5248",
+	"4.7.14": "RFC5248",
+	"4.7.15": "RFC6710",
+	"4.7.16": "RFC6710",
+	"4.7.17": "RFC7293",
+	"4.7.18": "RFC7293",
+	"4.7.19": "RFC7293",
+	"4.7.20": "RFC7372",
+	"4.7.21": "RFC7372",
+	"4.7.22": "RFC7372",
+	"4.7.23": "RFC7372",
+	"4.7.24": "RFC7372",
+	"4.7.25": "RFC7372",
+	"4.7.26": "RFC7372",
+	"4.7.27": "RFC7505",
+	"4.7.29": "RFC8617",
+	"4.7.30": "RFC8689",
+	"5.0.0":  "RFC3463",
+	"5.1.0":  "RFC3463",
+	"5.1.1":  "RFC3463",
+	"5.1.2":  "RFC3463",
+	"5.1.3":  "RFC3463",
+	"5.1.4":  "RFC3463",
+	"5.1.5":  "RFC3463",
+	"5.1.6":  "RFC3463",
+	"5.1.7":  "RFC3463",
+	"5.1.8":  "RFC3463",
+	"5.1.9":  "RFC3886",
+	"5.1.10": "RFC7505",
+	"5.2.0":  "RFC3463",
+	"5.2.1":  "RFC3463",
+	"5.2.2":  "RFC3463",
+	"5.2.3":  "RFC3463",
+	"5.2.4":  "RFC3463",
+	"5.3.0":  "RFC3463",
+	"5.3.1":  "RFC3463",
+	"5.3.2":  "RFC3463",
+	"5.3.3":  "RFC3463",
+	"5.3.4":  "RFC3463",
+	"5.3.5":  "RFC3463",
+	"5.3.6":  "RFC6710",
+	"5.4.0":  "RFC3463",
+	"5.4.1":  "RFC3463",
+	"5.4.2":  "RFC3463",
+	"5.4.3":  "RFC3463",
+	"5.4.4":  "RFC3463",
+	"5.4.5":  "RFC3463",
+	"5.4.6":  "RFC3463",
+	"5.4.7":  "RFC3463",
+	"5.5.0":  "RFC3463",
+	"5.5.1":  "RFC3463",
+	"5.5.2":  "RFC3463",
+	"5.5.3":  "RFC3463",
+	"5.5.4":  "RFC3463",
+	"5.5.5":  "RFC3463",
+	"5.5.6":  "RFC4954",
+	"5.6.0":  "RFC3463",
+	"5.6.1":  "RFC3463",
+	"5.6.2":  "RFC3463",
+	"5.6.3":  "RFC3463",
+	"5.6.4":  "RFC3463",
+	"5.6.5":  "RFC3463",
+	"5.6.6":  "RFC4468",
+	"5.6.7":  "RFC6531",
+	"5.6.8":  "RFC6531",
+	"5.6.9":  "RFC6531",
+	"5.6.10": "RFC6531",
+	"5.7.0":  "RFC3463",
+	"5.7.1":  "RFC3463",
+	"5.7.2":  "RFC3463",
+	"5.7.3":  "RFC3463",
+	"5.7.4":  "RFC3463",
+	"5.7.5":  "RFC3463",
+	"5.7.6":  "RFC3463",
+	"5.7.7":  "RFC3463",
+	"5.7.8":  "RFC4954",
+	"5.7.9":  "RFC4954",
+	"5.7.10": "RFC5248",
+	"5.7.11": "RFC4954",
+	"5.7.12": "RFC4954",
+	"5.7.13": "RFC5248",
+	"5.7.14": "RFC5248",
+	"5.7.15": "RFC6710",
+	"5.7.16": "RFC6710",
+	"5.7.17": "RFC7293",
+	"5.7.18": "RFC7293",
+	"5.7.19": "RFC7293",
+	"5.7.20": "RFC7372",
+	"5.7.21": "RFC7372",
+	"5.7.22": "RFC7372",
+	"5.7.23": "RFC7372",
+	"5.7.24": "RFC7372",
+	"5.7.25": "RFC7372",
+	"5.7.26": "RFC7372",
+	"5.7.27": "RFC7505",
+	"5.7.29": "RFC8617",
+	"5.7.30": "RFC8689",
+}
@@ -0,0 +1,218 @@
+package bouncespy
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TextRecipientsDetector is the name of the detector that finds the failed
+// recipients in the text of bounces that have no delivery status part
+const TextRecipientsDetector = "text-recipients"
+
+// textRecipientConfidence is the confidence of the reasons found in the
+// diagnostic lines of the recipients named in the text of a bounce
+const textRecipientConfidence = 0.6
+
+var (
+	// addressRe matches an email address that may be enclosed in angle
+	// brackets or preceded by mailto:
+	addressRe = regexp.MustCompile(`^(?:mailto:)?<?([^\s<>()"',;:@]+@[A-Za-z0-9.-]+\.[A-Za-z0-9-]+)>?[.,;:]?$`)
+	// namedAddressRe matches the "Name (address)" lines of Exchange
+	namedAddressRe = regexp.MustCompile(`^[^@]*\(([^\s()]+@[^\s()]+)\)$`)
+	// qmailRe matches the "<address>: diagnostic" lines of qmail and postfix
+	qmailRe = regexp.MustCompile(`^<([^\s<>]+@[^\s<>]+)>:\s*(.*)$`)
+	// exchangeRe matches the "Your message to address couldn't be delivered"
+	// lines of Exchange
+	exchangeRe = regexp.MustCompile(`(?i)your message to <?([^\s<>]+@[^\s<>]+?)>? couldn['’]?t be delivered`)
+)
+
+// recipientListMarkers are the lowercase phrases that introduce a list of
+// failed recipients, apart from the failed and delayed phrases of the phrase
+// catalogue
+var recipientListMarkers = []string{
+	"the following address(es) failed",
+	"the following address(es) deferred",
+	"following addresses had permanent fatal errors",
+	"following addresses had transient non-fatal errors",
+}
+
+// textRecipient is a recipient named in the text of a bounce along with the
+// diagnostic lines about it
+type textRecipient struct {
+	address     string
+	line        textLine
+	diagnostics []textLine
+}
+
+// normalizeAddress removes the angle brackets and the mailto: prefix of the
+// address and lowercases its domain
+func normalizeAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	addr = strings.TrimPrefix(addr, "mailto:")
+	addr = strings.Trim(addr, "<>")
+	if idx := strings.LastIndex(addr, "@"); idx >= 0 {
+		addr = addr[:idx] + strings.ToLower(addr[idx:])
+	}
+
+	return addr
+}
+
+// lineAddress returns the address of a line that starts with an address, such
+// as "foo@foo.foo" or the "foo@foo.foo (foo@foo.foo)" lines of Exchange, or
+// that contains a name followed by the address in parentheses.
+func lineAddress(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	if m := addressRe.FindStringSubmatch(fields[0]); m != nil {
+		return m[1]
+	}
+
+	if m := namedAddressRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
+
+// textRecipients returns the recipients named in the lines of the text of a
+// message in the known templates of the plain text bounces.
+func textRecipients(scan *textScan) []textRecipient {
+	lines := scan.lines
+	var result []textRecipient
+	for i := 0; i < len(lines); i++ {
+		line := lines[i].trim()
+		lower := scan.lowerLines[i]
+
+		if strings.Contains(lower, "couldn") {
+			if m := exchangeRe.FindStringSubmatch(line.text); m != nil {
+				result = append(result, textRecipient{address: m[1], line: line})
+				continue
+			}
+		}
+
+		var m []int
+		if strings.HasPrefix(line.text, "<") {
+			m = qmailRe.FindStringSubmatchIndex(line.text)
+		}
+
+		if m != nil {
+			r := textRecipient{address: line.text[m[2]:m[3]], line: line}
+			if m[4] < m[5] {
+				r.diagnostics = append(r.diagnostics, textLine{line.text[m[4]:m[5]], line.part, line.offset + m[4]})
+			}
+
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1].text) != "" && !isQmailLine(strings.TrimSpace(lines[i+1].text)) {
+				i++
+				r.diagnostics = append(r.diagnostics, lines[i].trim())
+			}
+
+			result = append(result, r)
+			continue
+		}
+
+		if !containsAny(lower, recipientListMarkers) &&
+			!containsPhrase(lower, failedPhrases) && !containsPhrase(lower, delayedPhrases) {
+			continue
+		}
+
+		// the list of recipients starts after the blank lines that follow
+		// the marker and ends with the next blank line
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1].text) == "" {
+			i++
+		}
+
+		var list []textRecipient
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1].text) != "" {
+			next := lines[i+1].trim()
+			if addr := lineAddress(next.text); addr != "" {
+				list = append(list, textRecipient{address: addr, line: next})
+			} else if len(list) > 0 {
+				list[len(list)-1].diagnostics = append(list[len(list)-1].diagnostics, next)
+			} else {
+				break
+			}
+			i++
+		}
+
+		result = append(result, list...)
+	}
+
+	return result
+}
+
+// isQmailLine reports whether the trimmed line is a "<address>: diagnostic"
+// line, looking for the regular expression only in the lines that may be one
+func isQmailLine(line string) bool {
+	return strings.HasPrefix(line, "<") && qmailRe.MatchString(line)
+}
+
+// diagnosticStatus returns the diagnostic line from its first status code on,
+// as it may have some text before them, such as "Remote host said: 550 5.1.1"
+func diagnosticStatus(line string) string {
+	parts := strings.Fields(line)
+	for i, p := range parts {
+		if parseStatus(p) != NotFound {
+			return strings.Join(parts[i:], " ")
+		}
+	}
+
+	return ""
+}
+
+// detectTextRecipients returns a candidate for every recipient named in the
+// text of the message or in its X-Failed-Recipients header. The reason of the
+// candidates is the one found in the diagnostic lines of each recipient, if
+// any, so the reason found for the message as a whole is used otherwise.
+func detectTextRecipients(m *Message) []Candidate {
+	var candidates []Candidate
+	for _, r := range textRecipients(m.scan()) {
+		c := Candidate{
+			Recipient:  normalizeAddress(r.address),
+			Reason:     NotFound,
+			Confidence: textRecipientConfidence,
+			Evidence:   r.line.text,
+			Location:   r.line.location(),
+		}
+
+		diagnostics := make([]string, len(r.diagnostics))
+		for i, d := range r.diagnostics {
+			diagnostics[i] = d.text
+		}
+
+		var found []Candidate
+		for i := len(r.diagnostics) - 1; i >= 0 && len(found) == 0; i-- {
+			d := r.diagnostics[i]
+			found = lineCandidates(diagnosticStatus(d.text), Candidate{
+				Recipient:  c.Recipient,
+				Diagnostic: strings.Join(diagnostics, " "),
+				Confidence: textRecipientConfidence,
+				Evidence:   d.text,
+				Location:   d.location(),
+			})
+		}
+
+		if len(found) == 0 {
+			found = []Candidate{c}
+		}
+
+		candidates = append(candidates, found...)
+	}
+
+	for _, value := range m.Header["X-Failed-Recipients"] {
+		for _, addr := range strings.Split(value, ",") {
+			if addr = normalizeAddress(addr); addr != "" {
+				candidates = append(candidates, Candidate{
+					Recipient:  addr,
+					Reason:     NotFound,
+					Confidence: textRecipientConfidence,
+					Evidence:   "X-Failed-Recipients: " + value,
+				})
+			}
+		}
+	}
+
+	return candidates
+}
@@ -0,0 +1,148 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"strings"
+
+	ch "gopkg.in/check.v1"
+)
+
+var qmailMsg = `Hi. This is the qmail-send program at mx.bar.bar.
+I'm afraid I wasn't able to deliver your message to the following addresses.
+This is a permanent error; I've given up. Sorry it didn't work out.
+
+<foo@FOO.foo>:
+1.1.1.1 does not like recipient.
+Remote host said: 550 5.1.1 User unknown
+Giving up on 1.1.1.1.
+
+<baz@foo.foo>:
+Remote host said: 552 5.2.2 Mailbox full
+
+--- Below this line is a copy of the message.
+`
+
+var postfixMsg = `This is the mail system at host mx.bar.bar.
+
+I'm sorry to have to inform you that your message could not
+be delivered to one or more recipients.
+
+<foo@foo.foo>: host mx.foo.foo[1.1.1.1] said: 550 5.7.1 Message rejected
+    (in reply to RCPT TO command)
+`
+
+var exchangeMsg = `Your message to foo@foo.foo couldn't be delivered.
+foo wasn't found at foo.foo.
+
+Delivery has failed to these recipients or groups:
+
+Foo Bar (foo@foo.foo)
+bar@foo.foo (bar@foo.foo)
+`
+
+func (s *BounceSuite) TestTextRecipients(c *ch.C) {
+	cases := []struct {
+		msg        string
+		recipients []string
+	}{
+		{msg1, []string{"foo@foo.foo"}},
+		{msg5, []string{"foo@foo.se"}},
+		{msg7, []string{"foo@foo.foo"}},
+		{eximMsg, []string{"foo@foo.foo"}},
+		{qmailMsg, []string{"foo@FOO.foo", "baz@foo.foo"}},
+		{postfixMsg, []string{"foo@foo.foo"}},
+		{exchangeMsg, []string{"foo@foo.foo", "foo@foo.foo", "bar@foo.foo"}},
+		{germanMsg, []string{"foo@foo.foo"}},
+		{msg3, nil},
+	}
+
+	for _, cs := range cases {
+		var recipients []string
+		for _, r := range textRecipients(NewMessage(mail.Header{}, []byte(cs.msg)).scan()) {
+			recipients = append(recipients, r.address)
+		}
+		c.Assert(recipients, ch.DeepEquals, cs.recipients, ch.Commentf("msg: %s", cs.msg))
+	}
+}
+
+func (s *BounceSuite) TestDetectTextRecipients(c *ch.C) {
+	candidates := detectTextRecipients(NewMessage(mail.Header{}, []byte(qmailMsg)))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{
+			Recipient:  "foo@foo.foo",
+			Reason:     BadDestinationMailboxAddress,
+			Diagnostic: "1.1.1.1 does not like recipient. Remote host said: 550 5.1.1 User unknown Giving up on 1.1.1.1.",
+			Confidence: textRecipientConfidence,
+			Evidence:   "Remote host said: 550 5.1.1 User unknown",
+			Location:   &Location{Part: 0, Offset: strings.Index(qmailMsg, "Remote host said: 550")},
+		},
+		{
+			Recipient:  "foo@foo.foo",
+			Reason:     MailboxUnavailable,
+			Diagnostic: "1.1.1.1 does not like recipient. Remote host said: 550 5.1.1 User unknown Giving up on 1.1.1.1.",
+			Confidence: textRecipientConfidence,
+			Evidence:   "Remote host said: 550 5.1.1 User unknown",
+			Location:   &Location{Part: 0, Offset: strings.Index(qmailMsg, "Remote host said: 550")},
+		},
+		{
+			Recipient:  "baz@foo.foo",
+			Reason:     MailboxFull,
+			Diagnostic: "Remote host said: 552 5.2.2 Mailbox full",
+			Confidence: textRecipientConfidence,
+			Evidence:   "Remote host said: 552 5.2.2 Mailbox full",
+			Location:   &Location{Part: 0, Offset: strings.Index(qmailMsg, "Remote host said: 552")},
+		},
+		{
+			Recipient:  "baz@foo.foo",
+			Reason:     ActionAbortedExceededStorageAlloc,
+			Diagnostic: "Remote host said: 552 5.2.2 Mailbox full",
+			Confidence: textRecipientConfidence,
+			Evidence:   "Remote host said: 552 5.2.2 Mailbox full",
+			Location:   &Location{Part: 0, Offset: strings.Index(qmailMsg, "Remote host said: 552")},
+		},
+	})
+
+	candidates = detectTextRecipients(NewMessage(mail.Header{"X-Failed-Recipients": {"foo@foo.foo, <Bar@FOO.foo>"}}, []byte("foo")))
+	c.Assert(candidates, ch.DeepEquals, []Candidate{
+		{Recipient: "foo@foo.foo", Reason: NotFound, Confidence: textRecipientConfidence, Evidence: "X-Failed-Recipients: foo@foo.foo, <Bar@FOO.foo>"},
+		{Recipient: "Bar@foo.foo", Reason: NotFound, Confidence: textRecipientConfidence, Evidence: "X-Failed-Recipients: foo@foo.foo, <Bar@FOO.foo>"},
+	})
+}
+
+func (s *BounceSuite) TestNormalizeAddress(c *ch.C) {
+	c.Assert(normalizeAddress(" <Foo@FOO.foo> "), Equals, "Foo@foo.foo")
+	c.Assert(normalizeAddress("mailto:foo@foo.foo"), Equals, "foo@foo.foo")
+	c.Assert(normalizeAddress("foo"), Equals, "foo")
+}
+
+func (s *BounceSuite) TestAnalyzeTextRecipients(c *ch.C) {
+	results := AnalyzeRecipients(mail.Header{}, []byte(qmailMsg))
+	c.Assert(results, ch.HasLen, 2)
+	c.Assert(results[0].Recipient, Equals, "foo@foo.foo")
+	c.Assert(results[0].Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(results[1].Recipient, Equals, "baz@foo.foo")
+	c.Assert(results[1].Reason, Equals, MailboxFull)
+
+	result := Analyze(mail.Header{}, []byte(postfixMsg))
+	c.Assert(result.Recipient, Equals, "foo@foo.foo")
+	c.Assert(result.Reason, Equals, MessageRefused)
+
+	result = Analyze(mail.Header{}, []byte(msg6))
+	c.Assert(result.Recipient, Equals, "foo@foo.foo")
+	c.Assert(result.Reason, Equals, UndefinedCode)
+}
+
+func (s *BounceSuite) TestDiagnosticStatus(c *ch.C) {
+	c.Assert(diagnosticStatus("Remote host said: 550 5.1.1 User unknown"), Equals, "550 5.1.1 User unknown")
+	c.Assert(diagnosticStatus("550  5.1.1 User unknown"), Equals, "550 5.1.1 User unknown")
+	c.Assert(diagnosticStatus("Giving up on 1.1.1.1."), Equals, "")
+
+	body := "The following address(es) failed:\n\n  foo@foo.foo\n    Remote host said: 550 5.1.1 User unknown\n"
+	candidates := detectTextRecipients(NewMessage(mail.Header{}, []byte(body)))
+	c.Assert(candidates, ch.HasLen, 2)
+	c.Assert(candidates[0].Recipient, Equals, "foo@foo.foo")
+	c.Assert(candidates[0].Reason, Equals, BadDestinationMailboxAddress)
+	c.Assert(candidates[0].Evidence, Equals, "Remote host said: 550 5.1.1 User unknown")
+	c.Assert(candidates[0].Location, ch.DeepEquals, &Location{Part: 0, Offset: strings.Index(body, "Remote host")})
+	c.Assert(candidates[1].Reason, Equals, MailboxUnavailable)
+}
@@ -0,0 +1,122 @@
+package bouncespy
+
+import (
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// VERPDetector is the name of the detector returned by NewVERPDetector
+const VERPDetector = "verp"
+
+// verpHeaders are the headers that contain the envelope recipient of a bounce
+// in the order they are used by default
+var verpHeaders = []string{"X-Original-To", "Delivered-To", "Envelope-To", "To"}
+
+// VERP is the format of variable envelope return paths, in which the address
+// of the recipient is encoded in the return path of the message, such as
+// bounces+alice=example.com@bounces.example.org for alice@example.com.
+type VERP struct {
+	// Prefix is the local part of the return path before the separator, such
+	// as "bounces". If it's empty, any local part is accepted.
+	Prefix string
+	// Separator separates the prefix from the encoded address. It's "+" if
+	// it's empty.
+	Separator string
+	// Equals replaces the @ of the encoded address. It's "=" if it's empty.
+	Equals string
+	// Headers are the headers the return path is looked for in. If it's
+	// empty X-Original-To, Delivered-To, Envelope-To and To are used.
+	Headers []string
+}
+
+// Decode returns the address encoded in the given return path and whether
+// the return path has the format of the VERP.
+func (v VERP) Decode(address string) (string, bool) {
+	separator, equals := v.Separator, v.Equals
+	if separator == "" {
+		separator = "+"
+	}
+
+	if equals == "" {
+		equals = "="
+	}
+
+	address = strings.Trim(strings.TrimSpace(address), "<>")
+	idx := strings.LastIndex(address, "@")
+	if idx < 0 {
+		return "", false
+	}
+	local := address[:idx]
+
+	if v.Prefix != "" {
+		if !strings.HasPrefix(strings.ToLower(local), strings.ToLower(v.Prefix+separator)) {
+			return "", false
+		}
+		local = local[len(v.Prefix+separator):]
+	} else {
+		idx := strings.Index(local, separator)
+		if idx < 0 {
+			return "", false
+		}
+		local = local[idx+len(separator):]
+	}
+
+	idx = strings.LastIndex(local, equals)
+	if idx <= 0 || idx+len(equals) >= len(local) {
+		return "", false
+	}
+
+	return local[:idx] + "@" + local[idx+len(equals):], true
+}
+
+// recipient returns the first address of the headers that can be decoded
+func (v VERP) recipient(header mail.Header) string {
+	headers := v.Headers
+	if len(headers) == 0 {
+		headers = verpHeaders
+	}
+
+	for _, key := range headers {
+		for _, value := range header[textproto.CanonicalMIMEHeaderKey(key)] {
+			for _, address := range headerAddresses(value) {
+				if rcpt, ok := v.Decode(address); ok {
+					return rcpt
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// headerAddresses returns the addresses of a header value. If the value is
+// not a valid address list it's used as a single address.
+func headerAddresses(value string) []string {
+	list, err := mail.ParseAddressList(value)
+	if err != nil {
+		return []string{value}
+	}
+
+	addresses := make([]string, len(list))
+	for i, addr := range list {
+		addresses[i] = addr.Address
+	}
+
+	return addresses
+}
+
+// NewVERPDetector returns a Detector that finds the recipient of the bounce
+// decoding its return path with the given VERP. The recipient has no reason,
+// so the reason found for the message as a whole is used for it when the
+// message does not name any other recipient with a reason.
+func NewVERPDetector(v VERP) Detector {
+	return NewDetector(VERPDetector, func(m *Message) []Candidate {
+		rcpt := v.recipient(m.Header)
+		if rcpt == "" {
+			return nil
+		}
+
+		return []Candidate{{Recipient: rcpt, Reason: NotFound}}
+	})
+}
@@ -0,0 +1,83 @@
+package bouncespy
+
+import (
+	"net/mail"
+
+	ch "gopkg.in/check.v1"
+)
+
+func (s *BounceSuite) TestVERPDecode(c *ch.C) {
+	cases := []struct {
+		verp    VERP
+		address string
+		rcpt    string
+		ok      bool
+	}{
+		{VERP{}, "bounces+alice=example.com@ourdomain.com", "alice@example.com", true},
+		{VERP{}, "<bounces+alice=example.com@ourdomain.com>", "alice@example.com", true},
+		{VERP{Prefix: "bounces"}, "Bounces+alice=example.com@ourdomain.com", "alice@example.com", true},
+		{VERP{Prefix: "bounces"}, "other+alice=example.com@ourdomain.com", "", false},
+		{VERP{Prefix: "b", Separator: "-", Equals: "#"}, "b-alice.smith#example.com@ourdomain.com", "alice.smith@example.com", true},
+		{VERP{}, "bounces+alice@ourdomain.com", "", false},
+		{VERP{}, "bounces+=example.com@ourdomain.com", "", false},
+		{VERP{}, "bounces+alice=@ourdomain.com", "", false},
+		{VERP{}, "bounces@ourdomain.com", "", false},
+		{VERP{}, "foo", "", false},
+	}
+
+	for _, cs := range cases {
+		rcpt, ok := cs.verp.Decode(cs.address)
+		c.Assert(ok, Equals, cs.ok, ch.Commentf("address: %s", cs.address))
+		c.Assert(rcpt, Equals, cs.rcpt, ch.Commentf("address: %s", cs.address))
+	}
+}
+
+func (s *BounceSuite) TestVERPDetector(c *ch.C) {
+	d := NewVERPDetector(VERP{Prefix: "bounces"})
+	c.Assert(d.Name(), Equals, VERPDetector)
+
+	header := mail.Header{
+		"To":            {"Our Bounces <bounces@ourdomain.com>"},
+		"Delivered-To":  {"foo@ourdomain.com", "bounces+alice=example.com@ourdomain.com"},
+		"X-Original-To": {"bounces+bob=example.com@ourdomain.com"},
+	}
+	c.Assert(d.Detect(NewMessage(header, []byte(msg6))), ch.DeepEquals, []Candidate{
+		{Recipient: "bob@example.com", Reason: NotFound},
+	})
+
+	d = NewVERPDetector(VERP{Prefix: "bounces", Headers: []string{"delivered-to"}})
+	c.Assert(d.Detect(NewMessage(header, []byte(msg6))), ch.DeepEquals, []Candidate{
+		{Recipient: "alice@example.com", Reason: NotFound},
+	})
+
+	c.Assert(d.Detect(NewMessage(mail.Header{}, []byte(msg6))), ch.HasLen, 0)
+}
+
+func (s *BounceSuite) TestAnalyzeVERP(c *ch.C) {
+	a := NewAnalyzer(append(DefaultDetectors(), NewVERPDetector(VERP{Prefix: "bounces"}))...)
+	header := mail.Header{"To": {"bounces+alice=example.com@ourdomain.com"}}
+
+	result := a.Analyze(NewMessage(header, []byte(msg1)))
+	c.Assert(result.Recipient, Equals, "alice@example.com")
+	c.Assert(result.Reason, Equals, MailboxUnavailable)
+	c.Assert(result.Type, Equals, Hard)
+
+	// the decoded recipient wins over the ones named in the message
+	results := a.AnalyzeRecipients(NewMessage(header, []byte(msg5)))
+	c.Assert(results, ch.HasLen, 1)
+	c.Assert(results[0].Recipient, Equals, "alice@example.com")
+	c.Assert(results[0].Reason, Equals, BadDestinationMailboxAddress)
+
+	dsnHeader, body := readMessage(c, dsnMsg)
+	dsnHeader["To"] = header["To"]
+	result = a.Analyze(NewMessage(dsnHeader, body))
+	c.Assert(result.Recipient, Equals, "alice@example.com")
+	c.Assert(result.Reason, Equals, BadDestinationMailboxAddress)
+
+	result = a.Analyze(NewMessage(header, []byte("foo")))
+	c.Assert(result.Recipient, Equals, "alice@example.com")
+	c.Assert(result.Reason, Equals, NotFound)
+
+	results = a.AnalyzeRecipients(NewMessage(readMessage(c, dsnMsg)))
+	c.Assert(results, ch.HasLen, 2)
+}